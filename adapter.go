@@ -0,0 +1,72 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"path"
+)
+
+// WrapMiddleware converts a net/http middleware into a MiddlewareFunc.
+//
+// The remaining middlewares and the controller are executed when the wrapped
+// middleware calls its next handler, with the request and response writer it
+// passes on. If it does not call the next handler the request is aborted.
+func WrapMiddleware(m func(http.Handler) http.Handler) MiddlewareFunc {
+	return func(c *Context) {
+		w := c.Response.ResponseWriter
+		called := false
+
+		next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			called = true
+			c.Request = req
+			c.Response.ResponseWriter = rw
+			c.Next()
+			c.Response.ResponseWriter = w
+		})
+		m(next).ServeHTTP(w, c.Request)
+
+		if !called {
+			c.Abort()
+		}
+	}
+}
+
+// WrapHandler converts a http.Handler into a ControllerFunc.
+func WrapHandler(h http.Handler) ControllerFunc {
+	return func(c *Context, r *Response) {
+		h.ServeHTTP(r, c.Request)
+	}
+}
+
+// Mount registers the given handler for all requests to paths below the given
+// prefix. The prefix is stripped from the request path before the handler is
+// called. The middlewares of the router are applied to the mounted handler.
+func (r *Router) Mount(prefix string, h http.Handler) {
+	controller := WrapHandler(http.StripPrefix(path.Join(r.pathPrefix, prefix), h))
+
+	for _, method := range []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"} {
+		r.Handle(method, path.Join(prefix, "/*filepath"), "", controller)
+	}
+}
+
+// Handler returns a http.Handler which executes the given controller and the
+// middlewares of the router, so it can be used with other multiplexers.
+func (r *Router) Handler(controller ControllerFunc) http.Handler {
+	route := &Route{
+		Path:       r.pathPrefix,
+		Controller: controller,
+		router:     r,
+
+		Requirements: append([]string(nil), r.requirements...),
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := r.kallisto.acquireContext(w, req, route)
+		defer r.kallisto.releaseContext(ctx)
+
+		ctx.Next()
+	})
+}
@@ -0,0 +1,105 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrapMiddleware(t *testing.T) {
+	k := New()
+
+	header := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Custom-Attr", "test")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey("user"), "admin")))
+		})
+	}
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("token") == "" {
+				http.Error(w, "denied", 401)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	called := false
+	k.Use(WrapMiddleware(header), WrapMiddleware(deny))
+	k.GET("/", "index", func(c *Context, r *Response) {
+		called = true
+		r.Text(c.Value(contextKey("user")).(string))
+	})
+
+	r, _ := http.NewRequest("GET", "/?token=secret", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Body.String() != "admin" {
+		t.Errorf("Body should be admin but got %s", w.Body.String())
+	}
+
+	if w.Header().Get("Custom-Attr") != "test" {
+		t.Errorf("Header Custom-Attr should be test but got %s", w.Header().Get("Custom-Attr"))
+	}
+
+	called = false
+	r, _ = http.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != 401 {
+		t.Errorf("Status should be 401 but got %d", w.Code)
+	}
+
+	if called {
+		t.Error("Controller should not be called.")
+	}
+}
+
+func TestMount(t *testing.T) {
+	k := New()
+	k.Use(func(c *Context) { c.Response.Header().Set("Custom-Attr", "test") })
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	})
+
+	k.Group("/api", "api::", func(r *Router) {
+		r.Mount("/legacy", h)
+	})
+
+	r, _ := http.NewRequest("POST", "/api/legacy/users/42", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Body.String() != "POST /users/42" {
+		t.Errorf("Body should be POST /users/42 but got %s", w.Body.String())
+	}
+
+	if w.Header().Get("Custom-Attr") != "test" {
+		t.Errorf("Header Custom-Attr should be test but got %s", w.Header().Get("Custom-Attr"))
+	}
+}
+
+func TestHandler(t *testing.T) {
+	k := New()
+	k.Use(func(c *Context) { c.Set("key", "value") })
+
+	mux := http.NewServeMux()
+	mux.Handle("/", k.Handler(func(c *Context, r *Response) { r.Text(c.Get("key").(string)) }))
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Body.String() != "value" {
+		t.Errorf("Body should be value but got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PrincipalKey is the context key under which the authentication middlewares
+// store the authenticated principal.
+const PrincipalKey = "Principal"
+
+// BasicVerifier checks the given user name and password and returns the
+// authenticated principal. The second return value reports whether the
+// credentials are valid.
+type BasicVerifier func(ctx *Context, user string, password string) (interface{}, bool)
+
+// TokenVerifier checks the given bearer token and returns the authenticated
+// principal. The second return value reports whether the token is valid.
+type TokenVerifier func(ctx *Context, token string) (interface{}, bool)
+
+// BasicAuth returns a middleware that authenticates requests with HTTP Basic
+// credentials (RFC 7617).
+//
+// The credentials are passed to the verifier. On success the returned principal
+// is stored on the context, otherwise the middleware replies with 401 and a
+// WWW-Authenticate challenge for the given realm and aborts the request.
+func BasicAuth(realm string, verify BasicVerifier) MiddlewareFunc {
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+
+	return func(c *Context) {
+		user, password, ok := c.Request.BasicAuth()
+		if ok {
+			if principal, valid := verify(c, user, password); valid {
+				c.Set(PrincipalKey, principal)
+				return
+			}
+		}
+
+		unauthorized(c, challenge)
+	}
+}
+
+// BearerAuth returns a middleware that authenticates requests with a bearer
+// token from the Authorization header (RFC 6750).
+//
+// The token is passed to the verifier. On success the returned principal is
+// stored on the context, otherwise the middleware replies with 401 and a
+// WWW-Authenticate challenge for the given realm and aborts the request.
+func BearerAuth(realm string, verify TokenVerifier) MiddlewareFunc {
+	challenge := "Bearer realm=" + strconv.Quote(realm)
+
+	return func(c *Context) {
+		token, ok := bearerToken(c.Request)
+		if !ok {
+			unauthorized(c, challenge)
+			return
+		}
+
+		principal, valid := verify(c, token)
+		if !valid {
+			unauthorized(c, challenge+`, error="invalid_token"`)
+			return
+		}
+
+		c.Set(PrincipalKey, principal)
+	}
+}
+
+// Principal returns the principal stored by an authentication middleware or
+// nil if the request is not authenticated.
+func (c *Context) Principal() interface{} {
+	return c.Get(PrincipalKey)
+}
+
+// bearerToken extracts the token of a "Bearer" Authorization header.
+func bearerToken(req *http.Request) (string, bool) {
+	auth := req.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[len(prefix):])
+	return token, token != ""
+}
+
+// unauthorized replies with 401 and the given challenge and aborts the request.
+func unauthorized(c *Context, challenge string) {
+	c.Response.Header().Set("WWW-Authenticate", challenge)
+	http.Error(c.Response, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	c.Abort()
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	k := New()
+	verify := func(c *Context, user string, password string) (interface{}, bool) {
+		return user, user == "admin" && password == "secret"
+	}
+	c := func(c *Context, r *Response) { r.Text("hello ", c.Principal().(string)) }
+
+	k.Group("/admin", "admin::", func(r *Router) {
+		r.GET("/", "index", c)
+	}, BasicAuth("admin area", verify))
+
+	r, _ := http.NewRequest("GET", "/admin", nil)
+	r.SetBasicAuth("admin", "secret")
+	check(k, r, t, "hello admin")
+
+	r, _ = http.NewRequest("GET", "/admin", nil)
+	r.SetBasicAuth("admin", "wrong")
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != 401 {
+		t.Errorf("Status should be 401 but got %d", w.Code)
+	}
+
+	challenge := `Basic realm="admin area", charset="UTF-8"`
+	if w.Header().Get("WWW-Authenticate") != challenge {
+		t.Errorf("WWW-Authenticate should be %s but got %s", challenge, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	k := New()
+	verify := func(c *Context, token string) (interface{}, bool) {
+		return "user", token == "valid"
+	}
+	c := func(c *Context, r *Response) { r.Text("hello ", c.Principal().(string)) }
+
+	k.GET("/api", "api", c).SetBefore(BearerAuth("api", verify))
+
+	r, _ := http.NewRequest("GET", "/api", nil)
+	r.Header.Set("Authorization", "Bearer valid")
+	check(k, r, t, "hello user")
+
+	tests := []struct {
+		header    string
+		challenge string
+	}{
+		{"", `Bearer realm="api"`},
+		{"Basic dXNlcjpwYXNz", `Bearer realm="api"`},
+		{"Bearer invalid", `Bearer realm="api", error="invalid_token"`},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/api", nil)
+		if test.header != "" {
+			r.Header.Set("Authorization", test.header)
+		}
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Code != 401 {
+			t.Errorf("Status should be 401 but got %d", w.Code)
+		}
+
+		if w.Header().Get("WWW-Authenticate") != test.challenge {
+			t.Errorf("WWW-Authenticate should be %s but got %s", test.challenge, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"strings"
+)
+
+// PolicyFunc is the signature of an authorization policy. It reports whether
+// the request of the given context may access the given route.
+type PolicyFunc func(*Context, *Route) bool
+
+// A RoleHolder is a principal which has roles or permissions.
+type RoleHolder interface {
+	// HasRole reports whether the principal has the given role or permission.
+	HasRole(role string) bool
+}
+
+// RolePolicy is the default policy. It grants access if the principal stored
+// on the context is a RoleHolder which has every requirement of the route.
+func RolePolicy(c *Context, r *Route) bool {
+	holder, ok := c.Principal().(RoleHolder)
+	if !ok {
+		return false
+	}
+
+	for _, requirement := range r.Requirements {
+		if !holder.HasRole(requirement) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasRole implements the RoleHolder interface. The roles of a token are taken
+// from its "roles" claim and its space separated "scope" claim.
+func (c *Claims) HasRole(role string) bool {
+	switch roles := c.Private["roles"].(type) {
+	case string:
+		if roles == role {
+			return true
+		}
+	case []interface{}:
+		for _, r := range roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+
+	if scope, ok := c.Private["scope"].(string); ok {
+		for _, s := range strings.Fields(scope) {
+			if s == role {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// authorize applies the policy of the application to routes with requirements.
+// If access is denied it replies with 403 and aborts the request.
+func (c *Context) authorize() bool {
+	if c.route == nil || len(c.route.Requirements) == 0 {
+		return true
+	}
+
+	policy := RolePolicy
+	if c.kallisto != nil && c.kallisto.policy != nil {
+		policy = c.kallisto.policy
+	}
+
+	if policy(c, c.route) {
+		return true
+	}
+
+	http.Error(c.Response, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	c.Abort()
+	return false
+}
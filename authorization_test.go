@@ -0,0 +1,102 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type user []string
+
+func (u user) HasRole(role string) bool {
+	for _, r := range u {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRequire(t *testing.T) {
+	k := New()
+	login := func(c *Context) { c.Set(PrincipalKey, user{c.Request.Header.Get("Role")}) }
+	c := func(c *Context, r *Response) { r.Text("granted") }
+
+	k.Use(login)
+	k.GET("/reports", "reports", c).Require("reporter")
+	k.Group("/admin", "admin::", func(r *Router) {
+		r.Require("admin")
+		r.GET("/users", "users", c).Require("users")
+	})
+
+	tests := []struct {
+		path   string
+		role   string
+		status int
+	}{
+		{"/reports", "reporter", 200},
+		{"/reports", "admin", 403},
+		{"/admin/users", "admin", 403},
+		{"/admin/users", "", 403},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", test.path, nil)
+		r.Header.Set("Role", test.role)
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("Status for %s as %s should be %d but got %d", test.path, test.role, test.status, w.Code)
+		}
+	}
+
+	requirements := []string{"admin", "users"}
+	if !reflect.DeepEqual(k.Routes()["admin::users"].Requirements, requirements) {
+		t.Errorf("Requirements should be %v but got %v", requirements, k.Routes()["admin::users"].Requirements)
+	}
+}
+
+func TestSetPolicy(t *testing.T) {
+	k := New()
+	c := func(c *Context, r *Response) { r.Text("granted") }
+	k.GET("/", "index", c).Require("anything")
+
+	k.SetPolicy(func(c *Context, r *Route) bool {
+		return c.Request.Header.Get("Allow") == r.Requirements[0]
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Allow", "anything")
+	check(k, r, t, "granted")
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != 403 {
+		t.Errorf("Status should be 403 but got %d", w.Code)
+	}
+}
+
+func TestClaimsHasRole(t *testing.T) {
+	claims := &Claims{Private: map[string]interface{}{
+		"roles": []interface{}{"admin"},
+		"scope": "read write",
+	}}
+
+	for _, role := range []string{"admin", "read", "write"} {
+		if !claims.HasRole(role) {
+			t.Errorf("Claims should have role %s", role)
+		}
+	}
+
+	if claims.HasRole("delete") {
+		t.Error("Claims should not have role delete")
+	}
+}
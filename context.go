@@ -5,9 +5,9 @@
 package kallisto
 
 import (
+	"context"
 	"net/http"
-
-	"github.com/julienschmidt/httprouter"
+	"time"
 )
 
 // A Context holds information for a HTTP request.
@@ -15,17 +15,27 @@ import (
 // It is passed to every middleware and controller that handles the
 // request to provide a state. Furthermore it stores a reference to all
 // middlewares and the controller.
+//
+// Context implements context.Context on top of the context of the request,
+// so it can be passed to any function that accepts a context.Context. It is
+// canceled when the client disconnects or a deadline set by the Timeout
+// middleware is exceeded.
 type Context struct {
+	// index is the position of the next handler in the compiled handler
+	// chain of the route. It is incremented before a handler is called.
+	index int
+
+	// aborted is set by Abort to stop the execution of any remaining
+	// middlewares and the controller.
+	aborted bool
 
-	// middlewareIndex is the index pointer for the MiddlewareChains stored
-	// in Route struct. It is initialized with -1 one and for each middleware called
-	// it will be incremented by one.
-	middlewareIndex int8
+	// released is set when the context is returned to the pool after the
+	// request was handled.
+	released bool
 
-	// doneBefore is a switch to signalzie if the before middlewares of the
-	// route param is are called. If so the controller will be executed
-	// and after that the after middlewares will be executed.
-	doneBefore bool
+	// retained prevents the context from being returned to the pool because
+	// it may still be in use, e.g. by a handler that exceeded its timeout.
+	retained bool
 
 	// kallisto stores a reference to the app.
 	kallisto *Kallisto
@@ -34,8 +44,8 @@ type Context struct {
 	// before and after middlewares and the controller.
 	route *Route
 
-	// Params is the httprouter paramter store.
-	Params httprouter.Params
+	// Params stores the route parameters of the request.
+	Params Params
 
 	// Request is a pointer to the http request.
 	Request *http.Request
@@ -56,12 +66,72 @@ type Data map[string]interface{}
 // newContext creates a Context struct with the given parameters and returns a pointer.
 func newContext(k *Kallisto, r *Route) *Context {
 	return &Context{
-		middlewareIndex: -1,
-		doneBefore:      false,
-		kallisto:        k,
-		route:           r,
-		Data:            make(Data),
+		kallisto: k,
+		route:    r,
+		Data:     make(Data),
+	}
+}
+
+// acquireContext returns a context from the pool of the application which is
+// reset for the given request.
+func (k *Kallisto) acquireContext(w http.ResponseWriter, req *http.Request, r *Route) *Context {
+	c := k.contextPool.Get().(*Context)
+	c.index = 0
+	c.aborted = false
+	c.released = false
+	c.retained = false
+	c.route = r
+	c.Params = c.Params[:0]
+	if cap(c.Params) < k.maxParams {
+		c.Params = make(Params, 0, k.maxParams)
+	}
+	c.Request = req
+	c.Response.ResponseWriter = w
+	c.Response.Renderer = nil
+	c.Response.status = 0
+	c.Response.defaultStatus = http.StatusOK
+	c.Session = nil
+	return c
+}
+
+// releaseContext returns the context to the pool of the application.
+// Contexts that are retained are left to the garbage collector.
+func (k *Kallisto) releaseContext(c *Context) {
+	if c.retained {
+		return
 	}
+
+	c.released = true
+	c.route = nil
+	c.Params = c.Params[:0]
+	c.Request = nil
+	c.Response.ResponseWriter = releasedWriter{}
+	c.Response.Renderer = nil
+	c.Session = nil
+	for key := range c.Data {
+		delete(c.Data, key)
+	}
+
+	k.contextPool.Put(c)
+}
+
+// Copy returns a copy of the context which may be used after the request
+// returned, e.g. in a goroutine. The copy has its own data store, no response
+// and can not continue the middleware chain.
+//
+// Contexts are reused for other requests, so neither the Context nor its
+// Response may be retained after the controller and middlewares returned.
+func (c *Context) Copy() *Context {
+	cp := *c
+	cp.aborted = true
+	cp.retained = true
+	cp.Response = nil
+	cp.Params = append(Params(nil), c.Params...)
+	cp.Data = make(Data, len(c.Data))
+	for key, value := range c.Data {
+		cp.Data[key] = value
+	}
+	return &cp
 }
 
 // Param returns a route param indentified by the given key.
@@ -69,27 +139,37 @@ func (c *Context) Param(key string) string {
 	return c.Params.ByName(key)
 }
 
-// Next calls the next middleware in the middleware stack or if appropriate
-// the controler and passes a pointer to itself to the middleware/controller.
+// Next executes the remaining handlers of the route, i.e. the wrapping and
+// before middlewares, the controller and the after middlewares, in order.
+//
+// Calling Next from a middleware runs the rest of the chain immediately and
+// returns afterwards, so the middleware can do work both before and after its
+// downstream handlers. If a middleware returns without calling Next the chain
+// continues with the following handler. Either way every handler is executed
+// at most once. Abort stops the chain.
 func (c *Context) Next() {
-	if c.middlewareIndex < int8(len(c.route.Before)-1) && !c.doneBefore {
-		c.middlewareIndex++
-		c.route.Before[c.middlewareIndex](c)
-		c.Next()
-	} else if c.middlewareIndex == int8(len(c.route.Before)-1) && !c.doneBefore {
-		c.doneBefore = true
-		c.middlewareIndex = -1
-
-		c.route.Controller(c, c.Response)
-
-		c.Next()
-	} else if c.middlewareIndex < int8(len(c.route.After)-1) && c.doneBefore {
-		c.middlewareIndex++
-		c.route.After[c.middlewareIndex](c)
-		c.Next()
+	if c.released {
+		panic("kallisto: Context used after the request returned")
+	}
+
+	handlers := c.route.handlers()
+	for c.index < len(handlers) && !c.aborted {
+		c.index++
+		handlers[c.index-1](c)
 	}
 }
 
+// Abort prevents the remaining middlewares and the controller from being called.
+// It does not stop the currently executed middleware.
+func (c *Context) Abort() {
+	c.aborted = true
+}
+
+// IsAborted reports whether Abort was called for this context.
+func (c *Context) IsAborted() bool {
+	return c.aborted
+}
+
 // Set stores the given key value pair.
 // Anything stored via Set is request scoped.
 func (c *Context) Set(key string, value interface{}) {
@@ -106,6 +186,43 @@ func (c *Context) App() *Kallisto {
 	return c.kallisto
 }
 
+// Context returns the context.Context of the request.
+func (c *Context) Context() context.Context {
+	if c.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
+// Deadline implements the context.Context interface.
+func (c *Context) Deadline() (time.Time, bool) {
+	return c.Context().Deadline()
+}
+
+// Done implements the context.Context interface.
+func (c *Context) Done() <-chan struct{} {
+	return c.Context().Done()
+}
+
+// Err implements the context.Context interface.
+func (c *Context) Err() error {
+	return c.Context().Err()
+}
+
+// Value implements the context.Context interface. String keys are looked up
+// in the request scoped data stored via Set first, all other keys and missing
+// values are looked up in the context of the request.
+//
+// Like Get it must not be called concurrently with Set.
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if v, ok := c.Data[k]; ok {
+			return v
+		}
+	}
+	return c.Context().Value(key)
+}
+
 // SetSession sets the given session and stores it in the current context.
 func (c *Context) SetSession(s Session) {
 	c.Session = s
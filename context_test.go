@@ -5,13 +5,13 @@
 package kallisto
 
 import (
+	"context"
+	"net/http"
 	"testing"
-
-	"github.com/julienschmidt/httprouter"
 )
 
 func TestParam(t *testing.T) {
-	c := &Context{Params: httprouter.Params{httprouter.Param{Key: "key", Value: "value"}}}
+	c := &Context{Params: Params{Param{Key: "key", Value: "value"}}}
 
 	if c.Param("key") != "value" {
 		t.Errorf("Param should be value but got %s", c.Param("key"))
@@ -39,10 +39,7 @@ func TestNext(t *testing.T) {
 	route.Controller = c
 	route.SetAfter(m2)
 
-	ctx := &Context{
-		middlewareIndex: -1,
-		route:           route,
-	}
+	ctx := &Context{route: route}
 
 	ctx.Next()
 
@@ -88,3 +85,93 @@ func TestGetSet(t *testing.T) {
 		t.Errorf("Value should be value but got %s", c.Get("key"))
 	}
 }
+
+func TestAbort(t *testing.T) {
+	called := false
+
+	route := &Route{}
+	route.SetBefore(func(c *Context) { c.Abort() })
+	route.Controller = func(c *Context, r *Response) { called = true }
+
+	ctx := &Context{route: route}
+
+	ctx.Next()
+
+	if called {
+		t.Error("Controller should not be called after Abort.")
+	}
+
+	if !ctx.IsAborted() {
+		t.Error("IsAborted should be true.")
+	}
+}
+
+type contextKey string
+
+func TestContext(t *testing.T) {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey("request"), "value"))
+	r, _ := http.NewRequest("GET", "/", nil)
+
+	c := &Context{Request: r.WithContext(parent), Data: make(Data)}
+	c.Set("key", "data")
+
+	var ctx context.Context = c
+
+	if ctx.Value("key") != "data" {
+		t.Errorf("Value should be data but got %v", ctx.Value("key"))
+	}
+
+	if ctx.Value(contextKey("request")) != "value" {
+		t.Errorf("Value should be value but got %v", ctx.Value(contextKey("request")))
+	}
+
+	if ctx.Err() != nil {
+		t.Errorf("Err should be nil but got %v", ctx.Err())
+	}
+
+	cancel()
+	<-ctx.Done()
+
+	if ctx.Err() != context.Canceled {
+		t.Errorf("Err should be %v but got %v", context.Canceled, ctx.Err())
+	}
+}
+
+func TestNextLongChain(t *testing.T) {
+	count := 0
+	route := &Route{Controller: func(c *Context, r *Response) {}}
+	for i := 0; i < 1000; i++ {
+		route.SetBefore(func(c *Context) { count++ })
+	}
+
+	ctx := &Context{route: route}
+	ctx.Next()
+
+	if count != 1000 {
+		t.Errorf("Count should be 1000 but got %d", count)
+	}
+}
+
+func TestNextFromMiddleware(t *testing.T) {
+	s := ""
+
+	route := &Route{}
+	route.SetBefore(
+		func(c *Context) {
+			s += "a"
+			c.Next()
+			c.Next()
+			s += ":a"
+		},
+		func(c *Context) { s += ":b" },
+	)
+	route.Controller = func(c *Context, r *Response) { s += ":controller" }
+	route.SetAfter(func(c *Context) { s += ":after" })
+
+	ctx := &Context{route: route}
+	ctx.Next()
+
+	if s != "a:b:controller:after:a" {
+		t.Errorf("Result should be a:b:controller:after:a but got %s", s)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attachment sends the content as file download with the given file name.
+//
+// The content type is derived from the file extension and Range requests are
+// supported. If the content has a Stat method like *os.File and fs.File, its
+// modification time and an ETag derived from it are used to answer
+// conditional requests. An ETag header set before is kept.
+func (r *Response) Attachment(name string, content io.ReadSeeker) {
+	r.serveContent("attachment", name, content)
+}
+
+// Inline sends the content like Attachment, but asks the browser to display
+// it instead of downloading it.
+func (r *Response) Inline(name string, content io.ReadSeeker) {
+	r.serveContent("inline", name, content)
+}
+
+// FileFromFS sends the file with the given name of the file system like
+// Inline, but without a Content-Disposition header. Missing files and
+// directories as well as invalid names result in a 404 Not Found. Files which
+// do not implement io.Seeker are streamed without support for Range and
+// conditional requests.
+func (r *Response) FileFromFS(fsys fs.FS, name string) {
+	r.serveFS(fsys, name, "")
+}
+
+// AttachmentFromFS sends the file with the given name of the file system as
+// file download named like the file.
+func (r *Response) AttachmentFromFS(fsys fs.FS, name string) {
+	r.serveFS(fsys, name, "attachment")
+}
+
+// serveFS sends a file of the file system with the given disposition.
+func (r *Response) serveFS(fsys fs.FS, name string, disposition string) {
+	f, err := fsys.Open(strings.TrimPrefix(name, "/"))
+	if err != nil {
+		r.fsError(err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		r.fsError(err)
+		return
+	}
+	if info.IsDir() {
+		r.fsError(fs.ErrNotExist)
+		return
+	}
+
+	modtime := r.setETag(info)
+	if content, ok := f.(io.ReadSeeker); ok {
+		r.send(disposition, path.Base(name), modtime, content)
+		return
+	}
+	r.stream(disposition, path.Base(name), modtime, info.Size(), f)
+}
+
+// stream sends the content of a file which can not seek while it is read, so
+// it is not buffered in memory. Range and conditional requests are not
+// supported, the whole file is sent.
+func (r *Response) stream(disposition string, name string, modtime time.Time, size int64, content io.Reader) {
+	h := r.Header()
+	if disposition != "" {
+		h.Set("Content-Disposition", contentDisposition(disposition, name))
+	}
+	if h.Get("Content-Type") == "" {
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h.Set("Content-Type", contentType)
+	}
+	if !modtime.IsZero() {
+		h.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+	}
+	h.Set("Content-Length", strconv.FormatInt(size, 10))
+	r.WriteHeader(http.StatusOK)
+
+	if r.ctx.Request.Method != http.MethodHead {
+		io.Copy(r, content)
+	}
+}
+
+// fsError replies with the status code matching the file system error.
+func (r *Response) fsError(err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrInvalid):
+		http.Error(r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(r, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// serveContent sends the content with the given disposition and file name.
+func (r *Response) serveContent(disposition string, name string, content io.ReadSeeker) {
+	var modtime time.Time
+	if s, ok := content.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		if info, err := s.Stat(); err == nil {
+			modtime = r.setETag(info)
+		}
+	}
+	r.send(disposition, name, modtime, content)
+}
+
+// send sends the content with the given disposition, file name and
+// modification time. http.ServeContent answers conditional and Range requests.
+func (r *Response) send(disposition string, name string, modtime time.Time, content io.ReadSeeker) {
+	if disposition != "" {
+		r.Header().Set("Content-Disposition", contentDisposition(disposition, name))
+	}
+	http.ServeContent(r, r.ctx.Request, name, modtime, content)
+}
+
+// setETag sets an ETag derived from the modification time and size of the
+// file unless it is already set and returns the modification time.
+func (r *Response) setETag(info fs.FileInfo) time.Time {
+	if !info.ModTime().IsZero() && r.Header().Get("ETag") == "" {
+		r.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	}
+	return info.ModTime()
+}
+
+// contentDisposition returns a Content-Disposition header value (RFC 6266)
+// with the file name. Names which are not plain ASCII are sent encoded as
+// UTF-8 in the filename* parameter with an ASCII fallback, in which every
+// other character is replaced by an underscore.
+func contentDisposition(disposition string, name string) string {
+	var fallback strings.Builder
+	plain := true
+	for _, c := range name {
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\\' || c == '%' {
+			c, plain = '_', false
+		}
+		fallback.WriteRune(c)
+	}
+
+	value := disposition + `; filename="` + fallback.String() + `"`
+	if !plain {
+		value += "; filename*=UTF-8''" + encodeRFC5987(name)
+	}
+	return value
+}
+
+// encodeRFC5987 percent-encodes all bytes of the string which are not an
+// attr-char of RFC 5987.
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
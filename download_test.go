@@ -0,0 +1,175 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAttachment(t *testing.T) {
+	k := New()
+	k.GET("/report", "", func(c *Context, r *Response) {
+		r.Attachment("Bericht März.csv", strings.NewReader("a,b\n1,2\n"))
+	})
+	k.GET("/report.txt", "", func(c *Context, r *Response) {
+		r.Inline("report.txt", strings.NewReader("report"))
+	})
+
+	r, _ := http.NewRequest("GET", "/report", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	disposition := `attachment; filename="Bericht M_rz.csv"; filename*=UTF-8''Bericht%20M%C3%A4rz.csv`
+	if w.Header().Get("Content-Disposition") != disposition {
+		t.Errorf("Content-Disposition should be %s but got %s", disposition, w.Header().Get("Content-Disposition"))
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Errorf("Content-Type should be text/csv but got %s", w.Header().Get("Content-Type"))
+	}
+	if w.Body.String() != "a,b\n1,2\n" {
+		t.Errorf("Unexpected body %q", w.Body.String())
+	}
+
+	r, _ = http.NewRequest("GET", "/report.txt", nil)
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Header().Get("Content-Disposition") != `inline; filename="report.txt"` {
+		t.Errorf("Content-Disposition should be inline; filename=\"report.txt\" but got %s", w.Header().Get("Content-Disposition"))
+	}
+}
+
+func TestAttachmentRange(t *testing.T) {
+	k := New()
+	k.GET("/file", "", func(c *Context, r *Response) {
+		r.Attachment("file.txt", strings.NewReader("0123456789"))
+	})
+
+	r, _ := http.NewRequest("GET", "/file", nil)
+	r.Header.Set("Range", "bytes=2-5")
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != http.StatusPartialContent || w.Body.String() != "2345" {
+		t.Errorf("Response should be 206 with 2345 but got %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Range") != "bytes 2-5/10" {
+		t.Errorf("Content-Range should be bytes 2-5/10 but got %s", w.Header().Get("Content-Range"))
+	}
+}
+
+func TestFileFromFS(t *testing.T) {
+	modtime := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"docs/manual.pdf": {Data: []byte("%PDF-1.4"), ModTime: modtime},
+		"docs/index.html": {Data: []byte("<h1>Docs</h1>"), ModTime: modtime},
+	}
+
+	k := New()
+	k.GET("/docs/*filepath", "", func(c *Context, r *Response) {
+		r.FileFromFS(fsys, "docs"+c.Param("filepath"))
+	})
+	k.GET("/download/*filepath", "", func(c *Context, r *Response) {
+		r.AttachmentFromFS(fsys, "docs"+c.Param("filepath"))
+	})
+
+	r, _ := http.NewRequest("GET", "/docs/index.html", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Body.String() != "<h1>Docs</h1>" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Unexpected response %s %s", w.Header().Get("Content-Type"), w.Body.String())
+	}
+	if w.Header().Get("Last-Modified") != "Sun, 01 May 2016 12:00:00 GMT" {
+		t.Errorf("Last-Modified should be Sun, 01 May 2016 12:00:00 GMT but got %s", w.Header().Get("Last-Modified"))
+	}
+	if w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Content-Disposition should be empty but got %s", w.Header().Get("Content-Disposition"))
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag should be set.")
+	}
+
+	r, _ = http.NewRequest("GET", "/docs/index.html", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Status should be 304 but got %d", w.Code)
+	}
+
+	r, _ = http.NewRequest("GET", "/docs/index.html", nil)
+	r.Header.Set("If-Modified-Since", "Mon, 02 May 2016 12:00:00 GMT")
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Status should be 304 but got %d", w.Code)
+	}
+
+	r, _ = http.NewRequest("GET", "/download/manual.pdf", nil)
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Header().Get("Content-Disposition") != `attachment; filename="manual.pdf"` {
+		t.Errorf("Content-Disposition should be attachment; filename=\"manual.pdf\" but got %s", w.Header().Get("Content-Disposition"))
+	}
+
+	for _, p := range []string{"/docs/missing.txt", "/docs/"} {
+		r, _ = http.NewRequest("GET", p, nil)
+		w = httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Status for %s should be 404 but got %d", p, w.Code)
+		}
+	}
+}
+
+// noSeekFS hides the Seek method of the files of a MapFS.
+type noSeekFS struct {
+	fstest.MapFS
+}
+
+func (fsys noSeekFS) Open(name string) (fs.File, error) {
+	f, err := fsys.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ fs.File }{f}, nil
+}
+
+func TestFileFromFSWithoutSeek(t *testing.T) {
+	fsys := noSeekFS{fstest.MapFS{"report.csv": {Data: []byte("a,b\n1,2\n")}}}
+
+	k := New()
+	k.GET("/report", "", func(c *Context, r *Response) {
+		r.AttachmentFromFS(fsys, "report.csv")
+	})
+
+	// The whole file is sent without support for Range requests.
+	r, _ := http.NewRequest("GET", "/report", nil)
+	r.Header.Set("Range", "bytes=2-5")
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || w.Body.String() != "a,b\n1,2\n" {
+		t.Errorf("Response should be 200 with the whole file but got %d %q", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Errorf("Content-Type should be text/csv but got %s", w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Content-Length") != "8" {
+		t.Errorf("Content-Length should be 8 but got %s", w.Header().Get("Content-Length"))
+	}
+	if w.Header().Get("Content-Disposition") != `attachment; filename="report.csv"` {
+		t.Errorf("Content-Disposition should be attachment; filename=\"report.csv\" but got %s", w.Header().Get("Content-Disposition"))
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// hostPattern is a compiled host pattern with its own route tree.
+type hostPattern struct {
+	// pattern is the host pattern as registered with lower case literal
+	// labels, e.g. "{tenant}.example.com".
+	pattern string
+
+	// re matches the host names of the pattern.
+	re *regexp.Regexp
+
+	// names are the names of the host parameters in order of their appearance.
+	names []string
+
+	// tree holds the routes of the host.
+	tree *tree
+}
+
+// hostParamPattern matches the parameters of a host pattern.
+var hostParamPattern = regexp.MustCompile(`\{([^{}.]+)\}`)
+
+// compileHostPattern compiles a host pattern. Parameters in curly braces match
+// exactly one label of the host name, e.g. "{tenant}.example.com". Literal
+// labels are lowercased, parameter names keep their case.
+func compileHostPattern(pattern string) *hostPattern {
+	h := &hostPattern{}
+
+	expr := "^"
+	last := 0
+	for _, m := range hostParamPattern.FindAllStringSubmatchIndex(pattern, -1) {
+		literal := strings.ToLower(pattern[last:m[0]])
+		h.pattern += literal + pattern[m[0]:m[1]]
+		expr += regexp.QuoteMeta(literal) + "([^.]+)"
+		h.names = append(h.names, pattern[m[2]:m[3]])
+		last = m[1]
+	}
+	literal := strings.ToLower(pattern[last:])
+	h.pattern += literal
+	expr += regexp.QuoteMeta(literal) + "$"
+
+	h.re = regexp.MustCompile(expr)
+	return h
+}
+
+// match reports whether the host name matches the pattern.
+func (h *hostPattern) match(host string) bool {
+	return h.re.MatchString(host)
+}
+
+// param returns the value of the host parameter with the given name.
+func (h *hostPattern) param(host string, name string) string {
+	m := h.re.FindStringSubmatch(host)
+	if m == nil {
+		return ""
+	}
+	for i, n := range h.names {
+		if n == name {
+			return m[i+1]
+		}
+	}
+	return ""
+}
+
+// build replaces the parameters of the pattern by the values of the given
+// key value pairs.
+func (h *hostPattern) build(params []string) string {
+	return hostParamPattern.ReplaceAllStringFunc(h.pattern, func(p string) string {
+		name := p[1 : len(p)-1]
+		for i := 0; i+1 < len(params); i += 2 {
+			if params[i] == name {
+				return params[i+1]
+			}
+		}
+		return p
+	})
+}
+
+// Host returns a router whose routes are only served for requests to host
+// names matching the given pattern. Parameters in curly braces match one label
+// of the host name and are available via Context.HostParam, e.g.
+// "{tenant}.example.com". The port of the request is ignored.
+//
+// Host names without parameters take precedence over patterns, which are
+// matched in the order of their registration, e.g. "api.example.com" is
+// served by its own routes even if "{tenant}.example.com" was registered
+// before. Requests to hosts without a matching pattern are served by the
+// routes which were registered outside of any host group, which therefore
+// act as the fallback host.
+func (r *Router) Host(pattern string, fn func(*Router)) {
+	host := r.kallisto.hostPattern(pattern)
+
+	fn(&Router{
+		kallisto:   r.kallisto,
+		parent:     r,
+		tree:       host.tree,
+		host:       host,
+		version:    r.version,
+		pathPrefix: r.pathPrefix,
+		namePrefix: r.namePrefix,
+
+		requirements: append([]string(nil), r.requirements...),
+	})
+}
+
+// hostPattern returns the registered host pattern or registers a new one.
+// Host names without parameters are kept in front of the patterns with
+// parameters, so they are matched first.
+func (k *Kallisto) hostPattern(pattern string) *hostPattern {
+	h := compileHostPattern(pattern)
+	for _, registered := range k.hosts {
+		if registered.pattern == h.pattern {
+			return registered
+		}
+	}
+
+	h.tree = &tree{}
+
+	i := len(k.hosts)
+	if len(h.names) == 0 {
+		for i > 0 && len(k.hosts[i-1].names) > 0 {
+			i--
+		}
+	}
+	k.hosts = append(k.hosts, nil)
+	copy(k.hosts[i+1:], k.hosts[i:])
+	k.hosts[i] = h
+	return h
+}
+
+// hostTree returns the route tree of the first host pattern matching the host
+// of the request or the tree of the fallback host. Host names without
+// parameters come first.
+func (k *Kallisto) hostTree(req *http.Request) *tree {
+	if len(k.hosts) > 0 {
+		host := hostname(req.Host)
+		for _, h := range k.hosts {
+			if h.match(host) {
+				return h.tree
+			}
+		}
+	}
+	return k.Router.tree
+}
+
+// HostParam returns the host parameter identified by the given key for routes
+// of a host group.
+func (c *Context) HostParam(key string) string {
+	if c.route == nil || c.route.host == nil {
+		return ""
+	}
+	return c.route.host.param(hostname(c.Request.Host), key)
+}
+
+// hostname returns the lower case host name without port.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,145 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	k := New()
+	k.GET("/", "home", func(c *Context, r *Response) { r.Text("home") })
+	k.Host("{tenant}.example.com", func(h *Router) {
+		h.GET("/", "tenant::home", func(c *Context, r *Response) { r.Text("tenant:", c.HostParam("tenant")) })
+		h.Group("/users", "tenant::users::", func(g *Router) {
+			g.GET("/:id", "show", func(c *Context, r *Response) {
+				r.Text(c.HostParam("tenant"), ":", c.Param("id"))
+			})
+		})
+	})
+	k.Host("api.example.com", func(h *Router) {
+		h.GET("/", "api::home", func(c *Context, r *Response) { r.Text("api") })
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Host = "acme.example.com:8080"
+	check(k, r, t, "tenant:acme")
+
+	r, _ = http.NewRequest("GET", "/users/42", nil)
+	r.Host = "ACME.example.com"
+	check(k, r, t, "acme:42")
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Host = "example.com"
+	check(k, r, t, "home")
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Host = "a.b.example.com"
+	check(k, r, t, "home")
+
+	// Host names without parameters take precedence over patterns.
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Host = "api.example.com"
+	check(k, r, t, "api")
+}
+
+func TestHostParamCase(t *testing.T) {
+	k := New()
+	k.Host("{tenantID}.Example.com", func(h *Router) {
+		h.GET("/", "home", func(c *Context, r *Response) { r.Text(c.HostParam("tenantID")) })
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Host = "Acme.EXAMPLE.com"
+	check(k, r, t, "acme")
+
+	if url := k.URL("home", "tenantID", "acme"); url != "//acme.example.com/" {
+		t.Errorf("URL should be //acme.example.com/ but got %s", url)
+	}
+}
+
+func TestHostFallback(t *testing.T) {
+	k := New()
+	k.GET("/about", "about", func(c *Context, r *Response) { r.Text("about") })
+	k.Host("admin.example.com", func(h *Router) {
+		h.POST("/users", "admin::users", func(c *Context, r *Response) {})
+	})
+
+	r, _ := http.NewRequest("GET", "/about", nil)
+	r.Host = "example.com"
+	check(k, r, t, "about")
+
+	// Host trees do not fall back to the routes of other hosts.
+	r, _ = http.NewRequest("GET", "/about", nil)
+	r.Host = "admin.example.com"
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status should be 404 but got %d", w.Code)
+	}
+
+	r, _ = http.NewRequest("GET", "/users", nil)
+	r.Host = "admin.example.com"
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Status should be 405 but got %d", w.Code)
+	}
+	if w.Header().Get("Allow") != "OPTIONS, POST" {
+		t.Errorf("Allow header should be OPTIONS, POST but got %s", w.Header().Get("Allow"))
+	}
+}
+
+func TestHostURL(t *testing.T) {
+	k := New()
+	k.Host("{tenant}.example.com", func(h *Router) {
+		h.GET("/users/:id", "users::show", func(c *Context, r *Response) {
+			r.Text(c.URL("users::show", "tenant", c.HostParam("tenant"), "id", "7"))
+		})
+	})
+
+	url := k.URL("users::show", "tenant", "acme", "id", "42")
+	if url != "//acme.example.com/users/42" {
+		t.Errorf("URL should be //acme.example.com/users/42 but got %s", url)
+	}
+
+	r, _ := http.NewRequest("GET", "/users/42", nil)
+	r.Host = "acme.example.com"
+	check(k, r, t, "//acme.example.com/users/7")
+
+	if k.Routes()["users::show"].Host != "{tenant}.example.com" {
+		t.Errorf("Host should be {tenant}.example.com but got %s", k.Routes()["users::show"].Host)
+	}
+}
+
+func TestHostConflicts(t *testing.T) {
+	k := New()
+	k.GET("/users", "", func(c *Context, r *Response) {})
+	k.Host("{tenant}.example.com", func(h *Router) {
+		h.GET("/users", "", func(c *Context, r *Response) {})
+	})
+	err := registerError(func() {
+		k.Host("{tenant}.example.com", func(h *Router) {
+			h.GET("/users", "", func(c *Context, r *Response) {})
+		})
+	})
+
+	if err == nil {
+		t.Fatal("Registering a conflicting route should panic with a RouteError.")
+	}
+	if err.Existing == nil || err.Existing.Host != "{tenant}.example.com" {
+		t.Errorf("Conflicting route should be the route of the host group but got %v", err.Existing)
+	}
+
+	var buf bytes.Buffer
+	k.PrintRoutes(&buf)
+	if !strings.Contains(buf.String(), "{tenant}.example.com/users") {
+		t.Errorf("Route table should contain the host of the route but got %s", buf.String())
+	}
+}
@@ -0,0 +1,514 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Supported JSON Web Token signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Errors returned while verifying a JSON Web Token.
+var (
+	ErrTokenMalformed       = errors.New("kallisto: malformed token")
+	ErrTokenAlgorithm       = errors.New("kallisto: unsupported token algorithm")
+	ErrTokenSignature       = errors.New("kallisto: invalid token signature")
+	ErrTokenExpired         = errors.New("kallisto: token is expired")
+	ErrTokenNotValidYet     = errors.New("kallisto: token is not valid yet")
+	ErrTokenIssuer          = errors.New("kallisto: invalid token issuer")
+	ErrTokenAudience        = errors.New("kallisto: invalid token audience")
+	ErrUnknownKey           = errors.New("kallisto: unknown key")
+	ErrKeyAlgorithmMismatch = errors.New("kallisto: key does not match algorithm")
+)
+
+// Claims holds the registered claims of a JSON Web Token (RFC 7519) as well as
+// all additional private claims.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt int64
+	NotBefore int64
+	IssuedAt  int64
+	ID        string
+
+	// Private holds all claims that are not registered claims.
+	Private map[string]interface{}
+}
+
+// HasAudience reports whether the given audience is one of the token audiences.
+func (c *Claims) HasAudience(audience string) bool {
+	for _, aud := range c.Audience {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON encodes the registered and private claims as one JSON object.
+func (c *Claims) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(c.Private)+7)
+	for k, v := range c.Private {
+		m[k] = v
+	}
+
+	if c.Issuer != "" {
+		m["iss"] = c.Issuer
+	}
+	if c.Subject != "" {
+		m["sub"] = c.Subject
+	}
+	if len(c.Audience) == 1 {
+		m["aud"] = c.Audience[0]
+	} else if len(c.Audience) > 1 {
+		m["aud"] = c.Audience
+	}
+	if c.ExpiresAt != 0 {
+		m["exp"] = c.ExpiresAt
+	}
+	if c.NotBefore != 0 {
+		m["nbf"] = c.NotBefore
+	}
+	if c.IssuedAt != 0 {
+		m["iat"] = c.IssuedAt
+	}
+	if c.ID != "" {
+		m["jti"] = c.ID
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes a JSON object into registered and private claims.
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+
+	*c = Claims{Private: make(map[string]interface{})}
+	for k, v := range m {
+		var ok bool
+		switch k {
+		case "iss":
+			c.Issuer, ok = v.(string)
+		case "sub":
+			c.Subject, ok = v.(string)
+		case "jti":
+			c.ID, ok = v.(string)
+		case "exp":
+			c.ExpiresAt, ok = numericDate(v)
+		case "nbf":
+			c.NotBefore, ok = numericDate(v)
+		case "iat":
+			c.IssuedAt, ok = numericDate(v)
+		case "aud":
+			c.Audience, ok = audience(v)
+		default:
+			c.Private[k], ok = v, true
+		}
+		if !ok {
+			return ErrTokenMalformed
+		}
+	}
+
+	return nil
+}
+
+// numericDate converts a decoded NumericDate claim to seconds since the epoch.
+func numericDate(v interface{}) (int64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	if i, err := n.Int64(); err == nil {
+		return i, true
+	}
+	f, err := n.Float64()
+	return int64(f), err == nil
+}
+
+// audience converts a decoded aud claim which is either a string or an array of strings.
+func audience(v interface{}) ([]string, bool) {
+	switch aud := v.(type) {
+	case string:
+		return []string{aud}, true
+	case []interface{}:
+		auds := make([]string, len(aud))
+		for i := range aud {
+			s, ok := aud[i].(string)
+			if !ok {
+				return nil, false
+			}
+			auds[i] = s
+		}
+		return auds, true
+	}
+	return nil, false
+}
+
+// A KeySet provides the keys to verify JSON Web Token signatures.
+type KeySet interface {
+	// Key returns the verification key with the given key id for the given algorithm.
+	Key(kid string, alg string) (interface{}, error)
+}
+
+// Keys is a static KeySet which maps key ids to keys.
+//
+// Supported keys are []byte for HS256, *rsa.PublicKey for RS256 and
+// *ecdsa.PublicKey for ES256. The empty key id is used for tokens without
+// a "kid" header.
+type Keys map[string]interface{}
+
+// Key implements the KeySet interface.
+func (k Keys) Key(kid string, alg string) (interface{}, error) {
+	key, ok := k[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// jwk is a single JSON Web Key (RFC 7517).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWKS reads a JSON Web Key Set from the given file.
+func LoadJWKS(fileName string) (Keys, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// ParseJWKS parses a JSON Web Key Set containing "oct", "RSA" and P-256 "EC" keys.
+func ParseJWKS(data []byte) (Keys, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(Keys, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.key()
+		if err != nil {
+			return nil, err
+		}
+		keys[k.Kid] = key
+	}
+
+	return keys, nil
+}
+
+// key decodes the key material of a JSON Web Key.
+func (k jwk) key() (interface{}, error) {
+	switch k.Kty {
+	case "oct":
+		return decodeSegment(k.K)
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, errors.New("kallisto: unsupported curve " + k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, errors.New("kallisto: unsupported key type " + k.Kty)
+}
+
+// JWTConfig configures the verification of JSON Web Tokens.
+type JWTConfig struct {
+	// Keys provides the verification keys.
+	Keys KeySet
+
+	// Issuer is the expected "iss" claim. It is not checked if empty.
+	Issuer string
+
+	// Audience is the expected "aud" claim. It is not checked if empty.
+	Audience string
+
+	// Leeway is the allowed clock skew for the "exp" and "nbf" claims.
+	Leeway time.Duration
+
+	// Realm is used for the WWW-Authenticate challenge.
+	Realm string
+
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// jwtHeader is the JOSE header of a JSON Web Token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// JWT returns a middleware that verifies the bearer token of a request as
+// a JSON Web Token and stores its claims as principal on the context. It
+// panics if the configuration has no keys.
+func JWT(config JWTConfig) MiddlewareFunc {
+	if config.Keys == nil {
+		panic("kallisto: JWT requires keys")
+	}
+
+	return BearerAuth(config.Realm, func(c *Context, token string) (interface{}, bool) {
+		claims, err := config.Verify(token)
+		if err != nil {
+			return nil, false
+		}
+		return claims, true
+	})
+}
+
+// Claims returns the claims stored by the JWT middleware or nil if the request
+// was not authenticated by a JSON Web Token.
+func (c *Context) Claims() *Claims {
+	claims, _ := c.Principal().(*Claims)
+	return claims
+}
+
+// Verify checks the signature and the claims of the given token and returns its claims.
+func (config JWTConfig) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeJSONSegment(parts[0], &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if config.Keys == nil {
+		return nil, ErrUnknownKey
+	}
+	key, err := config.Keys.Key(header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{}
+	if err := decodeJSONSegment(parts[1], claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	now := time.Now()
+	if config.Now != nil {
+		now = config.Now()
+	}
+
+	if claims.ExpiresAt != 0 && now.Add(-config.Leeway).Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	if claims.NotBefore != 0 && now.Add(config.Leeway).Unix() < claims.NotBefore {
+		return nil, ErrTokenNotValidYet
+	}
+
+	if config.Issuer != "" && claims.Issuer != config.Issuer {
+		return nil, ErrTokenIssuer
+	}
+
+	if config.Audience != "" && !claims.HasAudience(config.Audience) {
+		return nil, ErrTokenAudience
+	}
+
+	return claims, nil
+}
+
+// SignJWT creates a JSON Web Token with the given claims signed by the given
+// algorithm and key. The key id is added as "kid" header if it is not empty.
+//
+// Supported keys are []byte for HS256, *rsa.PrivateKey for RS256 and
+// *ecdsa.PrivateKey for ES256.
+func SignJWT(claims *Claims, alg string, kid string, key interface{}) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: alg, Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	input := encodeSegment(header) + "." + encodeSegment(payload)
+	hash := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return "", ErrKeyAlgorithmMismatch
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case RS256:
+		private, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return "", ErrKeyAlgorithmMismatch
+		}
+		signature, err = rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, hash[:])
+		if err != nil {
+			return "", err
+		}
+	case ES256:
+		private, ok := key.(*ecdsa.PrivateKey)
+		if !ok || private.Curve != elliptic.P256() {
+			return "", ErrKeyAlgorithmMismatch
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, private, hash[:])
+		if err != nil {
+			return "", err
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	default:
+		return "", ErrTokenAlgorithm
+	}
+
+	return input + "." + encodeSegment(signature), nil
+}
+
+// verifySignature checks the signature of the signing input with the given
+// algorithm and key. Private keys are accepted in place of their public keys.
+func verifySignature(alg string, key interface{}, input string, signature []byte) error {
+	hash := sha256.Sum256([]byte(input))
+
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrKeyAlgorithmMismatch
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrTokenSignature
+		}
+	case RS256:
+		if private, ok := key.(*rsa.PrivateKey); ok {
+			key = &private.PublicKey
+		}
+		public, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrKeyAlgorithmMismatch
+		}
+		if rsa.VerifyPKCS1v15(public, crypto.SHA256, hash[:], signature) != nil {
+			return ErrTokenSignature
+		}
+	case ES256:
+		if private, ok := key.(*ecdsa.PrivateKey); ok {
+			key = &private.PublicKey
+		}
+		public, ok := key.(*ecdsa.PublicKey)
+		if !ok || public.Curve != elliptic.P256() {
+			return ErrKeyAlgorithmMismatch
+		}
+		if len(signature) != 64 {
+			return ErrTokenSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(public, hash[:], r, s) {
+			return ErrTokenSignature
+		}
+	default:
+		return ErrTokenAlgorithm
+	}
+
+	return nil
+}
+
+// encodeSegment encodes a token segment with unpadded base64url.
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSegment decodes an unpadded base64url token segment.
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// decodeJSONSegment decodes a base64url token segment into v.
+func decodeJSONSegment(s string, v interface{}) error {
+	data, err := decodeSegment(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// decodeBigInt decodes a base64url encoded big-endian unsigned integer.
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := decodeSegment(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerifyJWT(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	secret := []byte("secret")
+
+	tests := []struct {
+		alg     string
+		private interface{}
+		public  interface{}
+	}{
+		{HS256, secret, secret},
+		{RS256, rsaKey, &rsaKey.PublicKey},
+		{ES256, ecKey, &ecKey.PublicKey},
+	}
+
+	for _, test := range tests {
+		claims := &Claims{
+			Subject:  "user",
+			Audience: []string{"api"},
+			Private:  map[string]interface{}{"admin": true},
+		}
+
+		token, err := SignJWT(claims, test.alg, "key", test.private)
+		if err != nil {
+			t.Fatalf("%s: SignJWT returned %v", test.alg, err)
+		}
+
+		config := JWTConfig{Keys: Keys{"key": test.public}, Audience: "api"}
+		verified, err := config.Verify(token)
+		if err != nil {
+			t.Fatalf("%s: Verify returned %v", test.alg, err)
+		}
+
+		if verified.Subject != "user" {
+			t.Errorf("%s: Subject should be user but got %s", test.alg, verified.Subject)
+		}
+
+		if verified.Private["admin"] != true {
+			t.Errorf("%s: Private claim admin should be true but got %v", test.alg, verified.Private["admin"])
+		}
+
+		if _, err := config.Verify(token[:len(token)-4] + "AAAA"); err != ErrTokenSignature {
+			t.Errorf("%s: Error should be %v but got %v", test.alg, ErrTokenSignature, err)
+		}
+	}
+}
+
+func TestVerifyJWTClaims(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1000, 0)
+	config := JWTConfig{
+		Keys:     Keys{"": secret},
+		Issuer:   "kallisto",
+		Audience: "api",
+		Leeway:   10 * time.Second,
+		Now:      func() time.Time { return now },
+	}
+
+	tests := []struct {
+		claims Claims
+		err    error
+	}{
+		{Claims{Issuer: "kallisto", Audience: []string{"api"}, ExpiresAt: 1005}, nil},
+		{Claims{Issuer: "kallisto", Audience: []string{"api"}, ExpiresAt: 995}, nil},
+		{Claims{Issuer: "kallisto", Audience: []string{"api"}, ExpiresAt: 990}, ErrTokenExpired},
+		{Claims{Issuer: "kallisto", Audience: []string{"api"}, NotBefore: 1005}, nil},
+		{Claims{Issuer: "kallisto", Audience: []string{"api"}, NotBefore: 1020}, ErrTokenNotValidYet},
+		{Claims{Issuer: "other", Audience: []string{"api"}}, ErrTokenIssuer},
+		{Claims{Issuer: "kallisto", Audience: []string{"web", "api"}}, nil},
+		{Claims{Issuer: "kallisto", Audience: []string{"web"}}, ErrTokenAudience},
+	}
+
+	for i, test := range tests {
+		token, _ := SignJWT(&test.claims, HS256, "", secret)
+
+		if _, err := config.Verify(token); err != test.err {
+			t.Errorf("%d: Error should be %v but got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestVerifyJWTRejectsKeyMismatch(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	token, _ := SignJWT(&Claims{}, HS256, "", []byte("secret"))
+
+	config := JWTConfig{Keys: Keys{"": &rsaKey.PublicKey}}
+	if _, err := config.Verify(token); err != ErrKeyAlgorithmMismatch {
+		t.Errorf("Error should be %v but got %v", ErrKeyAlgorithmMismatch, err)
+	}
+}
+
+func TestJWTWithoutKeys(t *testing.T) {
+	token, _ := SignJWT(&Claims{}, HS256, "", []byte("secret"))
+	if _, err := (JWTConfig{}).Verify(token); err != ErrUnknownKey {
+		t.Errorf("Error should be %v but got %v", ErrUnknownKey, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("JWT should panic without keys.")
+		}
+	}()
+	JWT(JWTConfig{})
+}
+
+func TestLoadJWKS(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	enc := base64.RawURLEncoding.EncodeToString
+
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "oct", "kid": "hmac", "k": "%s"},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "%s", "y": "%s"}
+	]}`, enc([]byte("secret")), enc(ecKey.X.Bytes()), enc(ecKey.Y.Bytes()))
+
+	fileName := filepath.Join(t.TempDir(), "jwks.json")
+	ioutil.WriteFile(fileName, []byte(jwks), 0600)
+
+	keys, err := LoadJWKS(fileName)
+	if err != nil {
+		t.Fatalf("LoadJWKS returned %v", err)
+	}
+
+	config := JWTConfig{Keys: keys}
+
+	token, _ := SignJWT(&Claims{Subject: "hmac"}, HS256, "hmac", []byte("secret"))
+	if _, err := config.Verify(token); err != nil {
+		t.Errorf("Verify of HS256 token returned %v", err)
+	}
+
+	token, _ = SignJWT(&Claims{Subject: "ec"}, ES256, "ec", ecKey)
+	if _, err := config.Verify(token); err != nil {
+		t.Errorf("Verify of ES256 token returned %v", err)
+	}
+
+	token, _ = SignJWT(&Claims{}, HS256, "unknown", []byte("secret"))
+	if _, err := config.Verify(token); err != ErrUnknownKey {
+		t.Errorf("Error should be %v but got %v", ErrUnknownKey, err)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	k := New()
+	secret := []byte("secret")
+	c := func(c *Context, r *Response) { r.Text(c.Claims().Subject) }
+
+	k.GET("/api", "api", c).SetBefore(JWT(JWTConfig{Keys: Keys{"": secret}, Realm: "api"}))
+
+	token, _ := SignJWT(&Claims{Subject: "user", ExpiresAt: time.Now().Add(time.Hour).Unix()}, HS256, "", secret)
+	r, _ := http.NewRequest("GET", "/api", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	check(k, r, t, "user")
+
+	token, _ = SignJWT(&Claims{Subject: "user", ExpiresAt: time.Now().Add(-time.Hour).Unix()}, HS256, "", secret)
+	r, _ = http.NewRequest("GET", "/api", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != 401 {
+		t.Errorf("Status should be 401 but got %d", w.Code)
+	}
+}
@@ -10,6 +10,7 @@ package kallisto
 import (
 	"net/http"
 	"sync"
+	"sync/atomic"
 )
 
 // Kallisto is the main struct of the web framework. It holds the router, all registered
@@ -21,20 +22,80 @@ type Kallisto struct {
 	// routes stores all registered routes identified by its name.
 	routes map[string]*Route
 
+	// routeList stores all registered routes in the order of their registration,
+	// including unnamed routes.
+	routeList []*Route
+
+	// notFound is the route of the custom NotFound handler.
+	notFound *Route
+
+	// methodNotAllowed is the route of the MethodNotAllowed handler.
+	methodNotAllowed *Route
+
+	// options is the route of the automatic OPTIONS handler.
+	options *Route
+
+	// panicHandler is the route of the custom PanicHandler.
+	panicHandler *Route
+
+	// hosts stores the host patterns of all host groups, host names without
+	// parameters first, otherwise in the order of their registration.
+	hosts []*hostPattern
+
+	// versions stores all API versions in the order of their registration.
+	versions []*APIVersion
+
+	// chainGeneration is incremented whenever middlewares are registered, so
+	// route chains are compiled again on their next request.
+	chainGeneration atomic.Uint64
+
+	// maxParams is the maximum number of parameters of the registered paths.
+	maxParams int
+
+	// methods stores all methods routes are registered for.
+	methods []string
+
+	// mounts stores the mounted applications with a custom NotFound handler.
+	mounts []mount
+
+	// errors stores the errors of routes registered with a duplicate name.
+	errors RouteErrors
+
 	sync.RWMutex // mutex for data
 	// data holds all stored application scoped data identified by a key.
 	data map[string]interface{}
 
 	// services stores all registered services identieid by a key.
 	services map[string]Runner
+
+	// policy decides if a request satisfies the requirements of a route.
+	policy PolicyFunc
+
+	// pathConfig configures the normalization of request paths.
+	pathConfig PathConfig
+
+	// contextPool stores unused contexts and their responses to be reused
+	// by later requests.
+	contextPool sync.Pool
 }
 
 // New is the constructor method for a kallisto application.
 func New() *Kallisto {
 	k := &Kallisto{routes: make(map[string]*Route)}
 	k.Router = NewRouter(k)
+	k.pathConfig = DefaultPathConfig()
+	k.NotFound(WrapHandler(http.NotFoundHandler()))
+	k.MethodNotAllowed(func(c *Context, r *Response) {
+		r.Text(http.StatusText(http.StatusMethodNotAllowed))
+	})
+	k.OptionsHandler(func(c *Context, r *Response) {})
 	k.data = make(map[string]interface{})
 	k.services = make(map[string]Runner)
+	k.contextPool.New = func() interface{} {
+		c := newContext(k, nil)
+		c.Response = newResponse(nil, c)
+		return c
+	}
 	return k
 }
 
@@ -63,6 +124,12 @@ func (k *Kallisto) Service(key string) interface{} {
 	return k.services[key]
 }
 
+// SetPolicy sets the policy which authorizes requests to routes with requirements.
+// By default RolePolicy is used.
+func (k *Kallisto) SetPolicy(p PolicyFunc) {
+	k.policy = p
+}
+
 // Route returns the path of the route identified by the given name.
 func (k *Kallisto) Route(name string) string {
 	if ok := k.routes[name]; ok != nil {
@@ -71,6 +138,31 @@ func (k *Kallisto) Route(name string) string {
 	return ""
 }
 
+// URL returns the path of the route identified by the given name with its
+// parameters replaced by the given key value pairs, e.g.
+// URL("user", "id", "42") returns "/users/42" for the path "/users/:id".
+// Parameter values are escaped. An empty string is returned if no route with
+// the given name exists or a required parameter is missing.
+//
+// For routes of a host group a protocol relative URL including the host is
+// returned, e.g. "//acme.example.com/users/42" for the host pattern
+// "{tenant}.example.com" and the parameters "tenant", "acme", "id", "42".
+func (k *Kallisto) URL(name string, params ...string) string {
+	route, ok := k.routes[name]
+	if !ok {
+		return ""
+	}
+
+	url, ok := buildURL(route.Path, params)
+	if !ok {
+		return ""
+	}
+	if route.host != nil {
+		url = "//" + route.host.build(params) + url
+	}
+	return url
+}
+
 // Routes returns all registered routes.
 func (k *Kallisto) Routes() map[string]*Route {
 	return k.routes
@@ -84,9 +176,15 @@ func (k *Kallisto) StartServices() {
 	}
 }
 
-// ListenAndServe starts the server and listens for requests to the given url.
-func (k *Kallisto) ListenAndServe(url string) {
+// ListenAndServe validates the application, starts the server and listens
+// for requests to the given url. It returns the error of Validate or the
+// error of http.ListenAndServe.
+func (k *Kallisto) ListenAndServe(url string) error {
+	if err := k.Validate(); err != nil {
+		return err
+	}
+
 	k.StartServices()
 
-	http.ListenAndServe(url, k)
+	return http.ListenAndServe(url, k)
 }
@@ -0,0 +1,145 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// mount is an application mounted under a path prefix which has a custom
+// NotFound handler.
+type mount struct {
+	prefix   string
+	notFound *Route
+}
+
+// MountApp merges the given application into the application of the router
+// under the given path and name prefixes.
+//
+// All routes of the mounted application are registered with prefixed paths and
+// names. Their middlewares are applied after those of the router, and the
+// NotFound handler of the mounted application handles all unmatched requests
+// below the path prefix. Services and application scoped data are added unless
+// the application already has a value for the same key.
+//
+// The mounted application is consumed by MountApp: its routes are moved into
+// the application of the router, which is then returned by Context.App.
+// Routes registered on the mounted application afterwards are not served.
+func (r *Router) MountApp(pathPrefix string, namePrefix string, app *Kallisto) {
+	r.Group(pathPrefix, namePrefix, func(g *Router) {
+		app.Router.parent = g
+
+		for _, route := range app.routeList {
+			route.Path = path.Join(g.pathPrefix, route.Path)
+			route.namespace = g.namePrefix + route.namespace
+			route.Group = g.namePrefix + route.Group
+			if route.Name != "" {
+				route.Name = g.namePrefix + route.Name
+			}
+			if route.host != nil {
+				route.host = r.kallisto.hostPattern(route.Host)
+			}
+			g.register(route)
+		}
+
+		for _, m := range app.mounts {
+			m.prefix = path.Join(g.pathPrefix, m.prefix)
+			r.kallisto.mounts = append(r.kallisto.mounts, m)
+		}
+
+		if app.notFound != nil {
+			r.kallisto.mounts = append(r.kallisto.mounts, mount{prefix: g.pathPrefix, notFound: app.notFound})
+		}
+	})
+
+	for key, service := range app.services {
+		if _, ok := r.kallisto.services[key]; !ok {
+			r.kallisto.services[key] = service
+		}
+	}
+
+	app.RLock()
+	defer app.RUnlock()
+	for key, value := range app.data {
+		if r.kallisto.Get(key) == nil {
+			r.kallisto.Set(key, value)
+		}
+	}
+
+	r.invalidateChains()
+}
+
+// URL returns the path of the route identified by the given name like
+// Kallisto.URL. Within a mounted application the name is resolved relative
+// to the application first, so its controllers can use their own route names.
+func (c *Context) URL(name string, params ...string) string {
+	if c.route != nil && c.route.namespace != "" {
+		if url := c.kallisto.URL(c.route.namespace+name, params...); url != "" {
+			return url
+		}
+	}
+	return c.kallisto.URL(name, params...)
+}
+
+// buildURL replaces the named and catch-all parameters of the given route path
+// by the escaped values of the given key value pairs. Optional parameters
+// without a value are omitted. It reports false if a required parameter is
+// missing.
+func buildURL(routePath string, params []string) (string, bool) {
+	if !strings.ContainsAny(routePath, ":*") {
+		return routePath, true
+	}
+
+	segments := strings.Split(routePath, "/")
+	built := segments[:0]
+	for _, segment := range segments {
+		if len(segment) < 2 || (segment[0] != ':' && segment[0] != '*') {
+			built = append(built, segment)
+			continue
+		}
+
+		name, optional := segment[1:], strings.HasSuffix(segment, "?")
+		if i := strings.IndexAny(name, "<?"); i >= 0 {
+			name = name[:i]
+		}
+
+		value, ok := "", false
+		for j := 0; j+1 < len(params); j += 2 {
+			if params[j] == name {
+				value, ok = params[j+1], true
+				break
+			}
+		}
+
+		switch {
+		case ok && segment[0] == '*':
+			// Catch-all values keep their slashes.
+			parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for i, part := range parts {
+				parts[i] = url.PathEscape(part)
+			}
+			built = append(built, strings.Join(parts, "/"))
+		case ok:
+			built = append(built, url.PathEscape(value))
+		case !optional:
+			return "", false
+		}
+	}
+
+	if len(built) == 1 {
+		return "/", true
+	}
+	return strings.Join(built, "/"), true
+}
+
+// hasPathPrefix reports whether the path is equal to the prefix or a path below it.
+func hasPathPrefix(p string, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMountApp(t *testing.T) {
+	admin := New()
+	admin.Use(func(c *Context) { c.Set("admin", "admin") })
+	admin.SetService("mailer", &service{})
+	admin.Set("title", "Admin")
+	admin.NotFound(func(c *Context, r *Response) { r.Text("admin not found") })
+	admin.Group("/users", "users::", func(r *Router) {
+		r.GET("/:id", "show", func(c *Context, r *Response) {
+			r.Text(c.Get("app").(string), ":", c.Get("admin").(string), ":", c.URL("users::show", "id", c.Param("id")))
+		})
+	})
+
+	k := New()
+	k.Use(func(c *Context) { c.Set("app", "app") })
+	k.NotFound(func(c *Context, r *Response) { r.Text("not found") })
+	k.MountApp("/admin", "admin::", admin)
+
+	r, _ := http.NewRequest("GET", "/admin/users/42", nil)
+	check(k, r, t, "app:admin:/admin/users/42")
+
+	if k.URL("admin::users::show", "id", "7") != "/admin/users/7" {
+		t.Errorf("URL should be /admin/users/7 but got %s", k.URL("admin::users::show", "id", "7"))
+	}
+
+	if k.Routes()["admin::users::show"] == nil {
+		t.Error("Mounted route should be registered with a prefixed name.")
+	}
+
+	if k.Service("mailer") == nil {
+		t.Error("Service of the mounted application should be registered.")
+	}
+
+	if k.Get("title") != "Admin" {
+		t.Errorf("Value should be Admin but got %v", k.Get("title"))
+	}
+
+	r, _ = http.NewRequest("GET", "/admin/missing", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != 404 || w.Body.String() != "admin not found" {
+		t.Errorf("Response should be 404 admin not found but got %d %s", w.Code, w.Body.String())
+	}
+
+	r, _ = http.NewRequest("GET", "/missing", nil)
+	check(k, r, t, "not found")
+}
+
+func TestURL(t *testing.T) {
+	k := New()
+	k.GET("/users/:id/files/*filepath", "file", func(c *Context, r *Response) {})
+
+	url := k.URL("file", "id", "42", "filepath", "/docs/a.txt")
+	if url != "/users/42/files/docs/a.txt" {
+		t.Errorf("URL should be /users/42/files/docs/a.txt but got %s", url)
+	}
+
+	url = k.URL("file", "id", "a b?admin=1#x", "filepath", "/my docs/a?.txt")
+	if url != "/users/a%20b%3Fadmin=1%23x/files/my%20docs/a%3F.txt" {
+		t.Errorf("URL should be escaped but got %s", url)
+	}
+
+	if k.URL("missing") != "" {
+		t.Errorf("URL should be empty but got %s", k.URL("missing"))
+	}
+
+	if k.URL("file", "id", "42") != "" {
+		t.Errorf("URL without a required parameter should be empty but got %s", k.URL("file", "id", "42"))
+	}
+}
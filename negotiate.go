@@ -0,0 +1,170 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Media types supported by Negotiate.
+const (
+	MIMEJSON    = "application/json"
+	MIMEXML     = "application/xml"
+	MIMETextXML = "text/xml"
+	MIMEHTML    = "text/html"
+	MIMEText    = "text/plain"
+)
+
+// A View is data which is rendered as HTML with the given templates by
+// Negotiate. Other media types encode only its data.
+//
+// Like Response.HTML the templates are rendered with the context data, so the
+// CSP nonce under NonceKey is available. Data of type Data is merged into the
+// context data, any other data is stored under ViewKey.
+type View struct {
+	Templates []string
+	Data      interface{}
+}
+
+// ViewKey is the context key under which Negotiate stores the data of a View
+// which is not of type Data, e.g. {{.View.Title}}.
+const ViewKey = "View"
+
+// Negotiate writes the data in the offered media type which is preferred by
+// the Accept header of the request, taking quality values into account. The
+// offers default to JSON, XML, HTML if the data is a View and a Renderer is
+// set, and plain text. Offers which are listed first win ties, as they do
+// for requests without an Accept header.
+//
+// JSON and XML are encoded by the encoding/json and encoding/xml packages,
+// HTML is rendered by the Renderer and plain text is formatted by fmt. HTML is
+// not offered without a Renderer. If no offer is acceptable the response is
+// 406 Not Acceptable. Negotiate panics if an offer is not one of the media
+// types above, regardless of the Accept header.
+func (r *Response) Negotiate(data interface{}, offers ...string) {
+	view, isView := data.(View)
+	if isView {
+		data = view.Data
+	}
+
+	if len(offers) == 0 {
+		offers = []string{MIMEJSON, MIMEXML}
+		if isView && r.Renderer != nil {
+			offers = append(offers, MIMEHTML)
+		}
+		offers = append(offers, MIMEText)
+	} else {
+		for _, offer := range offers {
+			switch offer {
+			case MIMEJSON, MIMEXML, MIMETextXML, MIMEHTML, MIMEText:
+			default:
+				panic("kallisto: Negotiate does not support the media type " + offer)
+			}
+		}
+		if r.Renderer == nil {
+			offers = removeString(offers, MIMEHTML)
+		}
+	}
+
+	r.Header().Add("Vary", "Accept")
+
+	offer := negotiate(r.ctx.Request.Header.Values("Accept"), offers)
+	switch offer {
+	case MIMEJSON:
+		r.encode(offer, json.Marshal, data)
+	case MIMEXML, MIMETextXML:
+		r.encode(offer, xml.Marshal, data)
+	case MIMEHTML:
+		switch d := data.(type) {
+		case Data:
+			r.HTML(d, view.Templates...)
+		case nil:
+			r.HTML(nil, view.Templates...)
+		default:
+			r.HTML(Data{ViewKey: d}, view.Templates...)
+		}
+	case MIMEText:
+		r.Text(fmt.Sprint(data))
+	default:
+		http.Error(r, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+	}
+}
+
+// negotiate returns the offer with the highest quality for the given Accept
+// header values or an empty string if no offer is acceptable. The first offer
+// is returned if there is no Accept header.
+func negotiate(accept []string, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if len(accept) == 0 {
+		return offers[0]
+	}
+
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		if q := quality(accept, offer); q > bestQuality {
+			best, bestQuality = offer, q
+		}
+	}
+	return best
+}
+
+// quality returns the quality value of the most specific media range of the
+// Accept header values which matches the offer.
+func quality(accept []string, offer string) float64 {
+	offerType, offerSubtype, _ := strings.Cut(offer, "/")
+
+	q, specificity := 0.0, -1
+	for _, value := range accept {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil {
+				continue
+			}
+
+			t, subtype, _ := strings.Cut(mediaType, "/")
+			s := 0
+			switch {
+			case t == offerType && subtype == offerSubtype:
+				s = 2
+			case t == offerType && subtype == "*":
+				s = 1
+			case t == "*" && subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s <= specificity {
+				continue
+			}
+
+			specificity, q = s, 1
+			if v, ok := params["q"]; ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+	}
+	return q
+}
+
+// removeString returns a copy of the list without the given string.
+func removeString(list []string, s string) []string {
+	removed := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != s {
+			removed = append(removed, v)
+		}
+	}
+	return removed
+}
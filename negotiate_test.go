@@ -0,0 +1,148 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type article struct {
+	Title string `json:"title" xml:"title"`
+}
+
+func (a article) String() string {
+	return a.Title
+}
+
+func TestNegotiate(t *testing.T) {
+	k := New()
+	k.GET("/article", "article", func(c *Context, r *Response) {
+		r.SetRenderer(renderer{})
+		r.Negotiate(View{Templates: []string{"article.html"}, Data: article{Title: "Hello"}})
+	})
+
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", MIMEJSON, `{"title":"Hello"}`},
+		{"application/json", MIMEJSON, `{"title":"Hello"}`},
+		{"application/xml", MIMEXML, `<article><title>Hello</title></article>`},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", MIMEHTML, b},
+		{"application/json;q=0.5, text/plain", MIMEText, "Hello"},
+		{"text/*;q=0.9, application/json;q=0.2", MIMEHTML, b},
+		{"*/*", MIMEJSON, `{"title":"Hello"}`},
+		{"application/*;q=0.5, application/json;q=0, text/plain;q=0.1", MIMEXML, `<article><title>Hello</title></article>`},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/article", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("Content-Type for %q should be %s but got %s", test.accept, test.contentType, w.Header().Get("Content-Type"))
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("Body for %q should be %s but got %s", test.accept, test.body, w.Body.String())
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Errorf("Vary should be Accept but got %s", w.Header().Get("Vary"))
+		}
+	}
+}
+
+// dataRenderer renders the nonce and the view data of the context data.
+type dataRenderer struct{}
+
+func (dataRenderer) Render(w io.Writer, v interface{}, t []string) {
+	data := v.(Data)
+	fmt.Fprintf(w, "%v %v", data[NonceKey], data[ViewKey])
+}
+
+func TestNegotiateHTMLData(t *testing.T) {
+	k := New()
+	k.GET("/article", "article", func(c *Context, r *Response) {
+		c.Set(NonceKey, "nonce")
+		r.SetRenderer(dataRenderer{})
+		r.Negotiate(View{Templates: []string{"article.html"}, Data: article{Title: "Hello"}})
+	})
+
+	r, _ := http.NewRequest("GET", "/article", nil)
+	r.Header.Set("Accept", "text/html")
+	check(k, r, t, "nonce Hello")
+}
+
+func TestNegotiateWithoutRenderer(t *testing.T) {
+	k := New()
+	k.GET("/article", "article", func(c *Context, r *Response) {
+		r.Negotiate(article{Title: "Hello"}, MIMEHTML, MIMEJSON)
+	})
+
+	r, _ := http.NewRequest("GET", "/article", nil)
+	check(k, r, t, `{"title":"Hello"}`)
+
+	r, _ = http.NewRequest("GET", "/article", nil)
+	r.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("Status should be 406 but got %d", w.Code)
+	}
+}
+
+func TestNegotiateOffers(t *testing.T) {
+	k := New()
+	k.GET("/article", "article", func(c *Context, r *Response) {
+		r.Negotiate(article{Title: "Hello"}, MIMEJSON, MIMETextXML)
+	})
+
+	r, _ := http.NewRequest("GET", "/article", nil)
+	r.Header.Set("Accept", "text/xml")
+	check(k, r, t, `<article><title>Hello</title></article>`)
+
+	// No offer is acceptable.
+	r, _ = http.NewRequest("GET", "/article", nil)
+	r.Header.Set("Accept", "text/html, text/plain;q=0")
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("Status should be 406 but got %d", w.Code)
+	}
+}
+
+func TestNegotiateUnsupportedOffer(t *testing.T) {
+	k := New()
+	k.GET("/article", "article", func(c *Context, r *Response) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Negotiate should panic for an unsupported offer.")
+			}
+		}()
+		r.Negotiate(article{Title: "Hello"}, MIMEJSON, "application/yaml")
+	})
+
+	// The first offer would be served without an Accept header.
+	r, _ := http.NewRequest("GET", "/article", nil)
+	k.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestJSONError(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := newResponse(w, ctx)
+	r.JSON(func() {})
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Status should be 500 but got %d", w.Code)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// TrailingSlashPolicy decides how requests are handled whose path only
+// matches a route with or without a trailing slash.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashRedirect redirects the request to the path of the route.
+	TrailingSlashRedirect TrailingSlashPolicy = iota
+
+	// TrailingSlashStrip serves the request by the route without a redirect.
+	TrailingSlashStrip
+
+	// TrailingSlashStrict handles the request by the NotFound handler.
+	TrailingSlashStrict
+)
+
+// PathConfig configures how request paths are matched against the routes.
+type PathConfig struct {
+	// TrailingSlash is the policy for paths which only match a route with or
+	// without a trailing slash.
+	TrailingSlash TrailingSlashPolicy
+
+	// CaseInsensitive matches the static parts of route paths regardless of
+	// their case. Parameter values keep the case of the request.
+	CaseInsensitive bool
+
+	// CleanPath redirects requests with duplicate slashes or dot segments,
+	// e.g. "//users/../posts", to the cleaned path if a route matches it.
+	CleanPath bool
+
+	// UseRawPath matches the escaped request path, so encoded slashes ("%2F")
+	// do not separate path segments. Parameter values are unescaped. Static
+	// parts of route paths must be registered in their escaped form.
+	UseRawPath bool
+
+	// RedirectCode is the status code of redirects of GET and HEAD requests,
+	// i.e. 301 or 308. It defaults to 301 if it is zero. Requests with other
+	// methods are always redirected with 308 Permanent Redirect, so they keep
+	// their method and body.
+	RedirectCode int
+}
+
+// DefaultPathConfig returns the path configuration of new applications. It
+// redirects to the path with or without a trailing slash and to cleaned paths
+// with 301 Moved Permanently.
+func DefaultPathConfig() PathConfig {
+	return PathConfig{
+		TrailingSlash: TrailingSlashRedirect,
+		CleanPath:     true,
+		RedirectCode:  http.StatusMovedPermanently,
+	}
+}
+
+// SetPathConfig sets the configuration for matching request paths of the
+// application. See DefaultPathConfig for the defaults. It panics if the
+// redirect code is not a 3xx status code.
+func (r *Router) SetPathConfig(config PathConfig) {
+	if config.RedirectCode == 0 {
+		config.RedirectCode = http.StatusMovedPermanently
+	}
+	if config.RedirectCode < http.StatusMultipleChoices || config.RedirectCode > http.StatusPermanentRedirect {
+		panic("kallisto: invalid redirect status code " + strconv.Itoa(config.RedirectCode))
+	}
+	r.kallisto.pathConfig = config
+}
+
+// dispatch serves the request with the route matching its host, method and
+// path. Unmatched requests are normalized according to the path configuration,
+// answered by the automatic OPTIONS or the MethodNotAllowed handler if the
+// path is registered for other methods or handled by the NotFound handler.
+func (k *Kallisto) dispatch(w http.ResponseWriter, req *http.Request) {
+	if k.panicHandler != nil {
+		defer k.recoverPanic(w, req)
+	}
+
+	t, p := k.hostTree(req), k.requestPath(req)
+	if k.serveRoute(w, req, t, p) {
+		return
+	}
+
+	if req.Method != http.MethodConnect && p != "/" {
+		if normalized, redirect := k.normalizePath(t, req.Method, p); normalized != "" {
+			if redirect {
+				k.redirectPath(w, req, normalized)
+				return
+			}
+			if k.serveRoute(w, req, t, normalized) {
+				return
+			}
+		}
+	}
+
+	if len(k.versions) > 0 && k.serveVersionFallback(w, req, t, p) {
+		return
+	}
+
+	if len(k.allowedMethods(req)) > 0 {
+		if req.Method == http.MethodOptions {
+			k.serveOptions(w, req)
+		} else {
+			k.serveMethodNotAllowed(w, req)
+		}
+		return
+	}
+
+	k.serveNotFound(w, req)
+}
+
+// serveRoute executes the route of the tree matching the request method and
+// the given path and reports whether a route matched.
+func (k *Kallisto) serveRoute(w http.ResponseWriter, req *http.Request, t *tree, p string) bool {
+	ctx := k.acquireContext(w, req, nil)
+	defer k.releaseContext(ctx)
+
+	ctx.route = k.match(t, req.Method, p, &ctx.Params)
+	if ctx.route == nil {
+		return false
+	}
+
+	if v := ctx.route.version; v != nil {
+		if v.scheme.kind != pathVersioning {
+			if ctx.route = ctx.route.selectVersion(req); ctx.route == nil {
+				k.serveNotFound(w, req)
+				return true
+			}
+		}
+		ctx.route.version.writeHeaders(w.Header())
+	}
+
+	if k.pathConfig.UseRawPath {
+		for i, param := range ctx.Params {
+			if value, err := url.PathUnescape(param.Value); err == nil {
+				ctx.Params[i].Value = value
+			}
+		}
+	}
+
+	ctx.Next()
+	return true
+}
+
+// match returns the route of the tree matching the method and path according
+// to the path configuration.
+func (k *Kallisto) match(t *tree, method string, p string, ps *Params) *Route {
+	return t.match(method, p, ps, k.pathConfig.CaseInsensitive)
+}
+
+// requestPath returns the path of the request which is matched against the routes.
+func (k *Kallisto) requestPath(req *http.Request) string {
+	if k.pathConfig.UseRawPath {
+		return req.URL.EscapedPath()
+	}
+	return req.URL.Path
+}
+
+// normalizePath returns the path with or without a trailing slash or the
+// cleaned path if a route of the tree matches it and the path configuration
+// allows it. It reports whether the request should be redirected to the path.
+func (k *Kallisto) normalizePath(t *tree, method string, p string) (string, bool) {
+	var ps Params
+	config := k.pathConfig
+
+	if config.TrailingSlash != TrailingSlashStrict {
+		if alt := toggleSlash(p); k.match(t, method, alt, &ps) != nil {
+			return alt, config.TrailingSlash == TrailingSlashRedirect
+		}
+	}
+
+	if !config.CleanPath {
+		return "", false
+	}
+
+	clean := path.Clean(p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	if clean == p {
+		return "", false
+	}
+
+	if k.match(t, method, clean, &ps) != nil {
+		return clean, true
+	}
+	if config.TrailingSlash != TrailingSlashStrict && clean != "/" {
+		if alt := toggleSlash(clean); k.match(t, method, alt, &ps) != nil {
+			return alt, true
+		}
+	}
+
+	return "", false
+}
+
+// redirectPath redirects the request to the given path, keeping its query.
+func (k *Kallisto) redirectPath(w http.ResponseWriter, req *http.Request, p string) {
+	code := http.StatusPermanentRedirect
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		code = k.pathConfig.RedirectCode
+	}
+
+	u := *req.URL
+	u.Path, u.RawPath = p, ""
+	if k.pathConfig.UseRawPath {
+		u.Path, _ = url.PathUnescape(p)
+		u.RawPath = p
+	}
+
+	http.Redirect(w, req, u.String(), code)
+}
+
+// toggleSlash adds a trailing slash to the path or removes it.
+func toggleSlash(p string) string {
+	if strings.HasSuffix(p, "/") {
+		return p[:len(p)-1]
+	}
+	return p + "/"
+}
+
+// recoverPanic executes the PanicHandler if the request handler panicked.
+func (k *Kallisto) recoverPanic(w http.ResponseWriter, req *http.Request) {
+	if rcv := recover(); rcv != nil {
+		k.servePanic(w, req, rcv)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrailingSlashPolicies(t *testing.T) {
+	tests := []struct {
+		policy TrailingSlashPolicy
+		code   int
+	}{
+		{TrailingSlashRedirect, http.StatusMovedPermanently},
+		{TrailingSlashStrip, http.StatusOK},
+		{TrailingSlashStrict, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		k := New()
+		config := DefaultPathConfig()
+		config.TrailingSlash = test.policy
+		k.SetPathConfig(config)
+		k.GET("/users", "", func(c *Context, r *Response) { r.Text("users") })
+
+		r, _ := http.NewRequest("GET", "/users/?page=2", nil)
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Code != test.code {
+			t.Errorf("Status for policy %d should be %d but got %d", test.policy, test.code, w.Code)
+		}
+		if test.policy == TrailingSlashRedirect && w.Header().Get("Location") != "/users?page=2" {
+			t.Errorf("Location should be /users?page=2 but got %s", w.Header().Get("Location"))
+		}
+		if test.policy == TrailingSlashStrip && w.Body.String() != "users" {
+			t.Errorf("Body should be users but got %s", w.Body.String())
+		}
+	}
+}
+
+func TestRedirectCode(t *testing.T) {
+	k := New()
+	config := DefaultPathConfig()
+	config.RedirectCode = http.StatusPermanentRedirect
+	k.SetPathConfig(config)
+	k.GET("/users", "", func(c *Context, r *Response) {})
+	k.POST("/users", "", func(c *Context, r *Response) {})
+
+	for _, method := range []string{"GET", "POST"} {
+		r, _ := http.NewRequest(method, "/users/", nil)
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Code != http.StatusPermanentRedirect {
+			t.Errorf("Status for %s should be 308 but got %d", method, w.Code)
+		}
+	}
+}
+
+func TestPartialPathConfig(t *testing.T) {
+	k := New()
+	k.SetPathConfig(PathConfig{CaseInsensitive: true})
+	k.GET("/users", "", func(c *Context, r *Response) {})
+
+	r, _ := http.NewRequest("GET", "/Users/", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/Users" {
+		t.Errorf("Request should be redirected with 301 to /Users but got %d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("SetPathConfig should panic for a redirect code which is not 3xx.")
+		}
+	}()
+	k.SetPathConfig(PathConfig{RedirectCode: http.StatusOK})
+}
+
+func TestCleanPath(t *testing.T) {
+	k := New()
+	k.GET("/users/:id", "", func(c *Context, r *Response) {})
+
+	r, _ := http.NewRequest("GET", "/users//42", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/users/42" {
+		t.Errorf("Request should be redirected to /users/42 but got %d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	r, _ = http.NewRequest("GET", "/posts/../users/42/", nil)
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/users/42" {
+		t.Errorf("Request should be redirected to /users/42 but got %d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	config := DefaultPathConfig()
+	config.CleanPath = false
+	k.SetPathConfig(config)
+
+	r, _ = http.NewRequest("GET", "/users//42", nil)
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status should be 404 but got %d", w.Code)
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	k := New()
+	k.GET("/Users/:name/posts", "", func(c *Context, r *Response) { r.Text(c.Param("name")) })
+
+	r, _ := http.NewRequest("GET", "/users/Alice/POSTS", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status should be 404 but got %d", w.Code)
+	}
+
+	config := DefaultPathConfig()
+	config.CaseInsensitive = true
+	k.SetPathConfig(config)
+
+	r, _ = http.NewRequest("GET", "/users/Alice/POSTS", nil)
+	check(k, r, t, "Alice")
+}
+
+func TestUseRawPath(t *testing.T) {
+	k := New()
+	k.GET("/files/:name", "", func(c *Context, r *Response) { r.Text(c.Param("name")) })
+
+	r, _ := http.NewRequest("GET", "/files/a%2Fb", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status should be 404 but got %d", w.Code)
+	}
+
+	config := DefaultPathConfig()
+	config.UseRawPath = true
+	k.SetPathConfig(config)
+
+	r, _ = http.NewRequest("GET", "/files/a%2Fb", nil)
+	check(k, r, t, "a/b")
+
+	r, _ = http.NewRequest("GET", "/files/a%2Fb/", nil)
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Header().Get("Location") != "/files/a%2Fb" {
+		t.Errorf("Location should be /files/a%%2Fb but got %s", w.Header().Get("Location"))
+	}
+}
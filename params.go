@@ -0,0 +1,196 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidUUID is returned by ParseUUID for malformed UUIDs.
+var ErrInvalidUUID = errors.New("kallisto: invalid UUID")
+
+// paramTypes maps the names of predefined parameter constraints to their patterns.
+var paramTypes = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"float": `-?[0-9]+(\.[0-9]+)?`,
+	"bool":  `true|false|1|0`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// Kinds of path segments.
+const (
+	staticSegment = iota
+	paramSegment
+	catchAllSegment
+)
+
+// A segment is a parsed segment of a route path.
+type segment struct {
+	kind int
+
+	// value is the path of a static segment or the name of a parameter.
+	value string
+
+	// expr is the constraint of a parameter as registered, e.g. "int".
+	expr string
+
+	// constraint restricts the values of a parameter.
+	constraint *regexp.Regexp
+
+	// optional is set for parameters which may be omitted.
+	optional bool
+}
+
+// parsePath splits the given route path into its segments.
+//
+// Named parameters match exactly one path segment, e.g. ":id", and catch-all
+// parameters the remaining path including its leading slash, e.g. "*filepath".
+// A catch-all parameter must be the last segment.
+//
+// A constraint follows the parameter name in angle brackets and is either the
+// name of a predefined type (int, uint, float, bool, alpha, alnum or uuid) or
+// a regular expression which must match the whole value, e.g. ":id<int>" or
+// ":slug<[a-z-]+>". Parameters followed by a question mark are optional, e.g.
+// "/posts/:page<int>?" matches "/posts" and "/posts/2". Only the last segments
+// of a path can be optional.
+func parsePath(routePath string) ([]segment, error) {
+	if routePath == "" || routePath[0] != '/' {
+		return nil, errors.New("path must begin with '/'")
+	}
+
+	parts := strings.Split(routePath[1:], "/")
+	segments := make([]segment, len(parts))
+	for i, part := range parts {
+		if part == "" || (part[0] != ':' && part[0] != '*') {
+			if i > 0 && segments[i-1].optional {
+				return nil, errors.New("optional parameters are only allowed at the end of the path")
+			}
+			if strings.ContainsAny(part, ":*") {
+				return nil, errors.New("wildcards must make up a whole path segment in " + strconv.Quote(part))
+			}
+			segments[i] = segment{kind: staticSegment, value: part}
+			continue
+		}
+
+		if part[0] == '*' {
+			if i != len(parts)-1 || (i > 0 && segments[i-1].optional) {
+				return nil, errors.New("catch-all parameters are only allowed at the end of the path")
+			}
+			if len(part) < 2 || strings.ContainsAny(part[1:], ":*<>?") {
+				return nil, errors.New("invalid catch-all parameter " + strconv.Quote(part))
+			}
+			segments[i] = segment{kind: catchAllSegment, value: part[1:]}
+			continue
+		}
+
+		s := segment{kind: paramSegment}
+		if strings.HasSuffix(part, "?") {
+			s.optional = true
+			part = part[:len(part)-1]
+		} else if i > 0 && segments[i-1].optional {
+			return nil, errors.New("optional parameters are only allowed at the end of the path")
+		}
+
+		s.value = part[1:]
+		if open := strings.IndexByte(part, '<'); open >= 0 {
+			if open < 2 || part[len(part)-1] != '>' {
+				return nil, errors.New("invalid parameter constraint in segment " + strconv.Quote(part))
+			}
+
+			s.value = part[1:open]
+			s.expr = part[open+1 : len(part)-1]
+			expr := s.expr
+			if t, ok := paramTypes[expr]; ok {
+				expr = t
+			}
+
+			pattern, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil {
+				return nil, err
+			}
+			s.constraint = pattern
+		}
+
+		if s.value == "" || strings.ContainsAny(s.value, ":*<>?") {
+			return nil, errors.New("invalid parameter name in segment " + strconv.Quote(part))
+		}
+		segments[i] = s
+	}
+
+	return segments, nil
+}
+
+// expandOptional returns the segments and all of their prefixes which omit
+// optional parameters.
+func expandOptional(segments []segment) [][]segment {
+	variants := [][]segment{segments}
+	for i, s := range segments {
+		if s.optional {
+			variants = append(variants, segments[:i])
+		}
+	}
+	return variants
+}
+
+// ParamInt returns the route param identified by the given key as int.
+func (c *Context) ParamInt(key string) (int, error) {
+	return strconv.Atoi(c.Param(key))
+}
+
+// ParamInt64 returns the route param identified by the given key as int64.
+func (c *Context) ParamInt64(key string) (int64, error) {
+	return strconv.ParseInt(c.Param(key), 10, 64)
+}
+
+// ParamUint returns the route param identified by the given key as uint64.
+func (c *Context) ParamUint(key string) (uint64, error) {
+	return strconv.ParseUint(c.Param(key), 10, 64)
+}
+
+// ParamFloat returns the route param identified by the given key as float64.
+func (c *Context) ParamFloat(key string) (float64, error) {
+	return strconv.ParseFloat(c.Param(key), 64)
+}
+
+// ParamBool returns the route param identified by the given key as bool.
+func (c *Context) ParamBool(key string) (bool, error) {
+	return strconv.ParseBool(c.Param(key))
+}
+
+// ParamUUID returns the route param identified by the given key as UUID.
+func (c *Context) ParamUUID(key string) (UUID, error) {
+	return ParseUUID(c.Param(key))
+}
+
+// A UUID is a universally unique identifier (RFC 4122).
+type UUID [16]byte
+
+// ParseUUID parses a UUID in its canonical form, e.g.
+// "123e4567-e89b-12d3-a456-426614174000".
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, ErrInvalidUUID
+	}
+
+	src := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(src)); err != nil {
+		return u, ErrInvalidUUID
+	}
+	return u, nil
+}
+
+// String returns the canonical form of the UUID.
+func (u UUID) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
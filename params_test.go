@@ -0,0 +1,97 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParamConstraints(t *testing.T) {
+	k := New()
+	c := func(c *Context, r *Response) { r.Text("found") }
+
+	k.GET("/users/:id<int>", "user", c)
+	k.GET("/posts/:slug<[a-z-]+>", "post", c)
+	k.GET("/files/:uuid<uuid>", "file", c)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/users/42", 200},
+		{"/users/-1", 200},
+		{"/users/abc", 404},
+		{"/posts/hello-world", 200},
+		{"/posts/Hello", 404},
+		{"/files/123e4567-e89b-12d3-a456-426614174000", 200},
+		{"/files/123e4567", 404},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", test.path, nil)
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("Status for %s should be %d but got %d", test.path, test.status, w.Code)
+		}
+	}
+
+	if k.URL("user", "id", "7") != "/users/7" {
+		t.Errorf("URL should be /users/7 but got %s", k.URL("user", "id", "7"))
+	}
+}
+
+func TestInvalidParamConstraint(t *testing.T) {
+	k := New()
+	err := registerError(func() {
+		k.GET("/users/:id<[0-9>", "user", func(c *Context, r *Response) {})
+	})
+
+	if err == nil {
+		t.Error("Registering an invalid constraint should panic with a RouteError.")
+	}
+}
+
+func TestTypedParams(t *testing.T) {
+	c := &Context{Params: Params{
+		Param{Key: "int", Value: "-42"},
+		Param{Key: "float", Value: "1.5"},
+		Param{Key: "bool", Value: "true"},
+		Param{Key: "uuid", Value: "123e4567-e89b-12d3-a456-426614174000"},
+		Param{Key: "invalid", Value: "abc"},
+	}}
+
+	if i, err := c.ParamInt("int"); i != -42 || err != nil {
+		t.Errorf("ParamInt should be -42 but got %d, %v", i, err)
+	}
+
+	if f, err := c.ParamFloat("float"); f != 1.5 || err != nil {
+		t.Errorf("ParamFloat should be 1.5 but got %f, %v", f, err)
+	}
+
+	if b, err := c.ParamBool("bool"); !b || err != nil {
+		t.Errorf("ParamBool should be true but got %t, %v", b, err)
+	}
+
+	u, err := c.ParamUUID("uuid")
+	if err != nil || u.String() != "123e4567-e89b-12d3-a456-426614174000" {
+		t.Errorf("ParamUUID should be 123e4567-e89b-12d3-a456-426614174000 but got %s, %v", u, err)
+	}
+
+	if _, err := c.ParamInt("invalid"); err == nil {
+		t.Error("ParamInt should return an error for abc.")
+	}
+
+	if _, err := c.ParamUint("int"); err == nil {
+		t.Error("ParamUint should return an error for -42.")
+	}
+
+	if _, err := c.ParamUUID("invalid"); err != ErrInvalidUUID {
+		t.Errorf("Error should be %v but got %v", ErrInvalidUUID, err)
+	}
+}
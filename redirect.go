@@ -0,0 +1,77 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Redirect replies to the request with a redirect to the given URL, which may
+// be a path relative to the request path. The code must be a 3xx status code.
+func (r *Response) Redirect(url string, code int) {
+	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
+		panic("kallisto: invalid redirect status code " + strconv.Itoa(code))
+	}
+	http.Redirect(r, r.ctx.Request, url, code)
+}
+
+// RedirectToRoute redirects to the route identified by the given name with
+// its parameters replaced by the given key value pairs like Context.URL.
+// GET and HEAD requests are redirected with 302 Found and all others with
+// 303 See Other, so the target is requested with GET. It panics if the route
+// does not exist or a required parameter is missing.
+func (r *Response) RedirectToRoute(name string, params ...string) {
+	target := r.ctx.URL(name, params...)
+	if target == "" {
+		panic("kallisto: no route named '" + name + "' or missing parameters")
+	}
+	r.Redirect(target, r.redirectCode())
+}
+
+// Back redirects to the page the request came from according to its Referer
+// header. The fallback URL is used if the Referer is missing or belongs to
+// another host, so Back can not be abused as open redirect.
+func (r *Response) Back(fallback string) {
+	target := fallback
+	if referer, err := url.Parse(r.ctx.Request.Referer()); err == nil && referer.Host == r.ctx.Request.Host {
+		referer.Scheme, referer.Host, referer.User = "", "", nil
+		if local := referer.String(); isLocalURL(local) {
+			target = local
+		}
+	}
+	r.Redirect(target, r.redirectCode())
+}
+
+// isLocalURL reports whether the URL is a path on the same host, i.e. whether
+// it does not begin with "//" or "/\", which browsers treat as a host.
+func isLocalURL(u string) bool {
+	return strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") && !strings.HasPrefix(u, "/\\")
+}
+
+// WithFlash stores the given value as flash in the session of the context, so
+// it is available for the request following a redirect, e.g.
+// r.WithFlash("User saved.").RedirectToRoute("users::show", "id", id).
+//
+// A session must have been set with Context.SetSession, usually by a session
+// middleware. WithFlash panics otherwise, as a missing session is a mistake
+// in the setup of the route rather than an error of the request.
+func (r *Response) WithFlash(v interface{}) *Response {
+	if r.ctx.Session == nil {
+		panic("kallisto: WithFlash requires a session")
+	}
+	r.ctx.Session.SetFlash(v)
+	return r
+}
+
+// redirectCode returns the status code for redirects to another page.
+func (r *Response) redirectCode() int {
+	if r.ctx.Request.Method == http.MethodGet || r.ctx.Request.Method == http.MethodHead {
+		return http.StatusFound
+	}
+	return http.StatusSeeOther
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type flashSession struct {
+	session
+	flash interface{}
+}
+
+func (s *flashSession) SetFlash(v interface{}) { s.flash = v }
+
+func TestRedirect(t *testing.T) {
+	k := New()
+	k.GET("/old", "", func(c *Context, r *Response) { r.Redirect("/new", http.StatusMovedPermanently) })
+
+	r, _ := http.NewRequest("GET", "/old", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/new" {
+		t.Errorf("Response should redirect with 301 to /new but got %d %s", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestRedirectInvalidCode(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Redirect should panic for a status code which is not 3xx.")
+		}
+	}()
+
+	w := httptest.NewRecorder()
+	r := newResponse(w, ctx)
+	r.Redirect("/", http.StatusOK)
+}
+
+func TestRedirectToRoute(t *testing.T) {
+	s := &flashSession{}
+
+	k := New()
+	k.GET("/users/:id", "users::show", func(c *Context, r *Response) {})
+	k.POST("/users", "users::create", func(c *Context, r *Response) {
+		c.SetSession(s)
+		r.WithFlash("User created.").RedirectToRoute("users::show", "id", "42")
+	})
+	k.GET("/users", "users::index", func(c *Context, r *Response) { r.RedirectToRoute("users::show", "id", "7") })
+
+	r, _ := http.NewRequest("POST", "/users", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/users/42" {
+		t.Errorf("Response should redirect with 303 to /users/42 but got %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if s.flash != "User created." {
+		t.Errorf("Flash should be User created. but got %v", s.flash)
+	}
+
+	r, _ = http.NewRequest("GET", "/users", nil)
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/users/7" {
+		t.Errorf("Response should redirect with 302 to /users/7 but got %d %s", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestBack(t *testing.T) {
+	k := New()
+	k.POST("/comments", "", func(c *Context, r *Response) { r.Back("/") })
+
+	tests := map[string]string{
+		"":                                  "/",
+		"http://example.com/posts/1?page=2": "/posts/1?page=2",
+		"http://evil.com/posts/1":           "/",
+		"http://example.com//evil.com":      "/",
+		"http://example.com/\\evil.com":     "/%5Cevil.com",
+	}
+
+	for referer, location := range tests {
+		r, _ := http.NewRequest("POST", "http://example.com/comments", nil)
+		r.Header.Set("Referer", referer)
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != location {
+			t.Errorf("Referer %s should redirect with 303 to %s but got %d %s", referer, location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
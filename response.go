@@ -5,6 +5,8 @@
 package kallisto
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"net/http"
 	"strings"
 )
@@ -18,6 +20,13 @@ type Response struct {
 	Renderer
 
 	ctx *Context
+
+	// status is the written status code. It is 0 until the header is written.
+	status int
+
+	// defaultStatus is written if the body is written before a status code
+	// was set. It defaults to 200.
+	defaultStatus int
 }
 
 // newResponse creates a Response struct and returns a pointer to it.
@@ -25,7 +34,37 @@ func newResponse(w http.ResponseWriter, ctx *Context) *Response {
 	return &Response{
 		ResponseWriter: w,
 		ctx:            ctx,
+		defaultStatus:  http.StatusOK,
+	}
+}
+
+// WriteHeader sends the header with the given status code. Only the first
+// call has an effect.
+func (r *Response) WriteHeader(code int) {
+	if r.status != 0 {
+		return
 	}
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the data to the response body. If no status code was written
+// yet the default status code is written first.
+func (r *Response) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(r.defaultStatus)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Status returns the written status code or 0 if the header is not written yet.
+func (r *Response) Status() int {
+	return r.status
+}
+
+// Written reports whether the header was written.
+func (r *Response) Written() bool {
+	return r.status != 0
 }
 
 // SetRenderer is the setter for a Renderer.
@@ -59,13 +98,43 @@ func (r *Response) Static(fileName string, req *http.Request) {
 }
 
 // JSON parses the given data to JSON and sets the appropriate header for
-// this content type.
+// this content type. Data which can not be encoded results in a 500 Internal
+// Server Error.
 func (r *Response) JSON(data interface{}) {
-	r.Header().Set("Content-Type", "application/json")
+	r.encode("application/json", json.Marshal, data)
 }
 
 // XML parses the given data to XML and sets the appropriate header for
-// this content type.
+// this content type. Data which can not be encoded results in a 500 Internal
+// Server Error.
 func (r *Response) XML(data interface{}) {
-	r.Header().Set("Content-Type", "text/xml")
+	r.encode("text/xml", xml.Marshal, data)
+}
+
+// encode writes the data encoded by the marshal function with the given
+// content type.
+func (r *Response) encode(contentType string, marshal func(interface{}) ([]byte, error), data interface{}) {
+	b, err := marshal(data)
+	if err != nil {
+		http.Error(r, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	r.Header().Set("Content-Type", contentType)
+	r.Write(b)
+}
+
+// releasedWriter is set as ResponseWriter of pooled responses to detect
+// responses that are used after the request returned.
+type releasedWriter struct{}
+
+func (releasedWriter) Header() http.Header {
+	panic("kallisto: Response used after the request returned")
+}
+
+func (releasedWriter) Write([]byte) (int, error) {
+	panic("kallisto: Response used after the request returned")
+}
+
+func (releasedWriter) WriteHeader(int) {
+	panic("kallisto: Response used after the request returned")
 }
@@ -4,12 +4,44 @@
 
 package kallisto
 
+import (
+	"sync/atomic"
+	"time"
+)
+
 // A Route holds all necessary route information.
-// This includes the path, before and after middleware as well as the controller.
+// This includes the path, wrapping, before and after middleware as well as the controller.
 type Route struct {
+	// Method is the HTTP method of the route.
+	Method string
+
 	// Path stores the full route path including group prefixes.
 	Path string
 
+	// Name is the full name of the route including group prefixes.
+	// It is empty for unnamed routes.
+	Name string
+
+	// Group is the name prefix of the group the route was registered in.
+	Group string
+
+	// Host is the host pattern of the host group the route was registered in.
+	// It is empty for routes which are served for any host.
+	Host string
+
+	// Version is the API version the route was registered for. It is empty
+	// for routes which are not versioned.
+	Version string
+
+	// Meta stores arbitrary metadata of the route, e.g. for documentation.
+	Meta map[string]interface{}
+
+	// Source is the file and line the route was registered at.
+	Source string
+
+	// Around is the middleware stack that wraps all other handlers of the route.
+	Around MiddlewareChain
+
 	// Before is the middleware stack that will be executed before the controller.
 	Before MiddlewareChain
 
@@ -18,23 +50,155 @@ type Route struct {
 
 	// After is the middleware stack that will be executed after the controller.
 	After MiddlewareChain
+
+	// Requirements are the roles or permissions a request needs to be granted
+	// by the policy of the application before the controller is executed.
+	Requirements []string
+
+	// Timeout overrides the duration of the Timeout middleware for this route.
+	Timeout time.Duration
+
+	// router is the router or group the route was registered on. Its
+	// middlewares and those of its parents are applied to the route.
+	router *Router
+
+	// compiled caches the compiledChain of the route.
+	compiled atomic.Value
+
+	// host is the compiled host pattern of the route.
+	host *hostPattern
+
+	// version is the API version of the route.
+	version *APIVersion
+
+	// versions are all versions of a route which is versioned by a request
+	// header, newest first. It is only set on the route which the tree stores
+	// in place of the versions of a path.
+	versions []*Route
+
+	// namespace is the name prefix of the application the route was mounted
+	// from. It is used to resolve route names relative to the route.
+	namespace string
+}
+
+// compiledChain is the handler chain of a route. It holds the wrapping and
+// before middlewares, the controller and the after middlewares.
+type compiledChain struct {
+	// app and generation identify the chain generation of the application
+	// the chain was compiled at.
+	app        *Kallisto
+	generation uint64
+
+	handlers MiddlewareChain
+}
+
+// SetAround registers the given middlewares as middlewares that wrap the
+// before middlewares, the controller and the after middlewares.
+//
+// A wrapping middleware calls Context.Next to run its downstream handlers and
+// can do work before and after that call, e.g. measure the duration of a
+// request, commit a transaction or recover from a panic. Use Context.Abort to
+// skip the downstream handlers.
+func (r *Route) SetAround(middlewares ...MiddlewareFunc) *Route {
+	r.Around = append(r.Around, middlewares...)
+	r.compiled.Store((*compiledChain)(nil))
+	return r
 }
 
 // SetBefore registers the given middlewares as middlewares that will be
 // called before the controller method is executed.
 func (r *Route) SetBefore(middlewares ...MiddlewareFunc) *Route {
-	// There could be already some before middleware registered by the router.
 	r.Before = append(r.Before, middlewares...)
+	r.compiled.Store((*compiledChain)(nil))
 	return r
 }
 
 // SetAfter registers the given middlewares as middlewares that will be
 // called after the controller method is executed.
 func (r *Route) SetAfter(middlewares ...MiddlewareFunc) *Route {
-	r.After = middlewares
+	r.After = append(r.After, middlewares...)
+	r.compiled.Store((*compiledChain)(nil))
+	return r
+}
+
+// Require adds the given roles or permissions to the requirements of the route.
+func (r *Route) Require(requirements ...string) *Route {
+	r.Requirements = append(r.Requirements, requirements...)
+	return r
+}
+
+// SetMeta stores the given metadata value identified by the given key.
+func (r *Route) SetMeta(key string, value interface{}) *Route {
+	if r.Meta == nil {
+		r.Meta = make(map[string]interface{})
+	}
+	r.Meta[key] = value
 	return r
 }
 
+// SetTimeout overrides the duration of the Timeout middleware for this route.
+func (r *Route) SetTimeout(d time.Duration) *Route {
+	r.Timeout = d
+	return r
+}
+
+// handlers returns the handler chain of the route. It is compiled on the
+// first request and again after middlewares were registered on the route or
+// anywhere in its application.
+func (r *Route) handlers() MiddlewareChain {
+	var app *Kallisto
+	var generation uint64
+	if r.router != nil {
+		app = r.router.root()
+	}
+	if app != nil {
+		generation = app.chainGeneration.Load()
+	}
+	if c, _ := r.compiled.Load().(*compiledChain); c != nil && c.app == app && c.generation == generation {
+		return c.handlers
+	}
+
+	handlers := r.compile()
+	r.compiled.Store(&compiledChain{app: app, generation: generation, handlers: handlers})
+	return handlers
+}
+
+// compile builds the handler chain of the route.
+//
+// Wrapping and before middlewares of the routers are applied from the outermost
+// router to the innermost group, followed by those of the route itself. After
+// middlewares are applied in reverse, so the middlewares of the route run first.
+func (r *Route) compile() MiddlewareChain {
+	var routers []*Router
+	for router := r.router; router != nil; router = router.parent {
+		routers = append([]*Router{router}, routers...)
+	}
+
+	var around, before, after MiddlewareChain
+	for _, router := range routers {
+		around = append(around, router.around...)
+		before = append(before, router.middlewares...)
+		after = append(append(MiddlewareChain(nil), router.after...), after...)
+	}
+
+	handlers := make(MiddlewareChain, 0, len(around)+len(r.Around)+len(before)+len(r.Before)+len(r.After)+len(after)+1)
+	handlers = append(handlers, around...)
+	handlers = append(handlers, r.Around...)
+	handlers = append(handlers, before...)
+	handlers = append(handlers, r.Before...)
+	handlers = append(handlers, callController)
+	handlers = append(handlers, r.After...)
+	handlers = append(handlers, after...)
+	return handlers
+}
+
+// callController executes the controller of the route if the request is authorized.
+func callController(c *Context) {
+	if c.authorize() {
+		c.route.Controller(c, c.Response)
+	}
+}
+
 // NewRoute initializes and returns a pointer to a Route struct.
 func NewRoute() *Route {
 	return &Route{
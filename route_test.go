@@ -55,3 +55,51 @@ func compareMiddlewareFunc(m1 MiddlewareChain, m2 MiddlewareChain) bool {
 
 	return true
 }
+
+func TestAround(t *testing.T) {
+	s := ""
+
+	r := &Route{}
+	r.SetAround(func(c *Context) {
+		s += "begin"
+		c.Next()
+		s += ":end"
+	})
+	r.SetBefore(func(c *Context) { s += ":before" })
+	r.Controller = func(c *Context, r *Response) { s += ":controller" }
+	r.SetAfter(func(c *Context) { s += ":after" })
+
+	ctx := &Context{route: r}
+	ctx.Next()
+
+	if s != "begin:before:controller:after:end" {
+		t.Errorf("Result should be begin:before:controller:after:end but got %s", s)
+	}
+}
+
+func TestCompiledChains(t *testing.T) {
+	k := New()
+	route := k.GET("/", "index", func(c *Context, r *Response) {})
+	compiled := route.handlers()
+
+	// Middlewares of another application do not affect the route.
+	New().Use(m1)
+	if c := route.handlers(); &c[0] != &compiled[0] {
+		t.Error("Chain should not be compiled again after middlewares of another application were registered.")
+	}
+
+	k.Use(m1)
+	if c := route.handlers(); len(c) != len(compiled)+1 {
+		t.Errorf("Chain should have %d handlers but got %d", len(compiled)+1, len(c))
+	}
+
+	// Routes of a mounted application are compiled with the middlewares of
+	// the application they are mounted into.
+	app := New()
+	mounted := app.GET("/", "index", func(c *Context, r *Response) {})
+	length := len(mounted.handlers())
+	k.MountApp("/app", "app::", app)
+	if c := mounted.handlers(); len(c) != length+1 {
+		t.Errorf("Chain of mounted route should have %d handlers but got %d", length+1, len(c))
+	}
+}
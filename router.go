@@ -7,23 +7,32 @@ package kallisto
 import (
 	"net/http"
 	"path"
-
-	"github.com/julienschmidt/httprouter"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // Router is a http.Handler and can be used with any net/http packages which
 // dispatches requests to matching routes.
-//
-// It is a wrapper struct for Julien Schmidts httprouter.
 type Router struct {
 	// Holds all registered middlewares and applies them to every handler.
 	middlewares MiddlewareChain
 
+	// Holds all registered after middlewares and applies them to every handler.
+	after MiddlewareChain
+
+	// Holds all registered wrapping middlewares and applies them to every handler.
+	around MiddlewareChain
+
 	// Holds a reference to the main application.
 	kallisto *Kallisto
 
-	// httprouter holds a reference to Julien Schmidts router.
-	httprouter *httprouter.Router
+	// parent is the router a group was created from. The middlewares of all
+	// parents are applied to the routes of a group.
+	parent *Router
+
+	// tree holds the routes of the host of the router.
+	tree *tree
 
 	// Can be set to prepend a common path prefix to all registered routes.
 	// This is used in route groups.
@@ -32,8 +41,22 @@ type Router struct {
 	// Can be set to prepend a common name prefix to all registered routes.
 	// This is used in route groups.
 	namePrefix string
+
+	// Holds all requirements that are added to every route of the router.
+	requirements []string
+
+	// host is the host pattern of a host group. It is nil for routes which
+	// are served for any host.
+	host *hostPattern
+
+	// version is the API version of a version group.
+	version *APIVersion
 }
 
+// AllowedMethodsKey is the context key under which the allowed methods of the
+// requested path are stored for the MethodNotAllowed and OptionsHandler controllers.
+const AllowedMethodsKey = "AllowedMethods"
+
 // MiddlewareFunc is the signature of a middleware function.
 type MiddlewareFunc func(*Context)
 
@@ -47,14 +70,56 @@ type ControllerFunc func(*Context, *Response)
 func NewRouter(k *Kallisto) *Router {
 	return &Router{
 		kallisto:    k,
-		httprouter:  httprouter.New(),
+		tree:        &tree{},
 		middlewares: make([]MiddlewareFunc, 0),
 	}
 }
 
-// Use registers middleware for all routes of the router.
+// root returns the application at the top of the parent chain of the router.
+// It serves the routes of all applications mounted into it.
+func (r *Router) root() *Kallisto {
+	for r.parent != nil {
+		r = r.parent
+	}
+	return r.kallisto
+}
+
+// invalidateChains causes all route chains of the application to be compiled
+// again on their next request.
+func (r *Router) invalidateChains() {
+	if k := r.root(); k != nil {
+		k.chainGeneration.Add(1)
+	}
+}
+
+// Use registers middleware for all routes of the router and its groups.
+// The middlewares are called before the controller. They also apply to
+// routes which were registered before.
 func (r *Router) Use(middlewares ...MiddlewareFunc) {
-	r.middlewares = middlewares
+	r.middlewares = append(r.middlewares, middlewares...)
+	r.invalidateChains()
+}
+
+// UseAfter registers middleware for all routes of the router and its groups
+// which is called after the controller. It also applies to routes which were
+// registered before.
+func (r *Router) UseAfter(middlewares ...MiddlewareFunc) {
+	r.after = append(r.after, middlewares...)
+	r.invalidateChains()
+}
+
+// Around registers wrapping middlewares for all routes of the router and its
+// groups. See Route.SetAround.
+func (r *Router) Around(middlewares ...MiddlewareFunc) {
+	r.around = append(r.around, middlewares...)
+	r.invalidateChains()
+}
+
+// Require adds the given roles or permissions to the requirements of all
+// routes registered afterwards on this router and its groups.
+func (r *Router) Require(requirements ...string) *Router {
+	r.requirements = append(r.requirements, requirements...)
+	return r
 }
 
 // GET registers HTTP GET request handles for the specified path.
@@ -107,79 +172,294 @@ func (r *Router) OPTIONS(path string, name string, controller ControllerFunc) *R
 }
 
 // NotFound sets a controller as a custom NotFound handler.
+//
+// The middlewares of the router are applied to the controller. The status code
+// defaults to 404, but the controller can set another status code and headers.
 func (r *Router) NotFound(c ControllerFunc) {
+	route := NewRoute()
+	route.Controller = c
+	route.router = r
+
+	r.kallisto.notFound = route
+}
+
+// serveNotFound executes the NotFound handler of the mounted application with
+// the longest path prefix matching the request or the custom NotFound handler
+// of the application.
+func (k *Kallisto) serveNotFound(w http.ResponseWriter, req *http.Request) {
+	route := k.notFound
+	longest := -1
+	for _, m := range k.mounts {
+		if len(m.prefix) > longest && hasPathPrefix(req.URL.Path, m.prefix) {
+			route = m.notFound
+			longest = len(m.prefix)
+		}
+	}
+
+	if route == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	k.serveStatus(w, req, route, http.StatusNotFound, http.StatusNotFound, "", nil)
+}
 
+// MethodNotAllowed sets a controller as a custom handler for requests to a
+// registered path with a method that is not registered for it. The allowed
+// methods are sent in the Allow header and are available via
+// Context.AllowedMethods. The status code defaults to 405.
+func (r *Router) MethodNotAllowed(c ControllerFunc) {
 	route := NewRoute()
 	route.Controller = c
+	route.router = r
 
-	r.httprouter.NotFound = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		w.WriteHeader(404)
-		ctx := newContext(r.kallisto, route)
-		ctx.Request = req
-		ctx.Response = newResponse(w, ctx)
-		ctx.Next()
-	})
+	r.kallisto.methodNotAllowed = route
 }
 
-// PanicHandler is a wrapper for the httprouter PanicHandler method to work
-// with a ControllerFunc.
-func (r *Router) PanicHandler(c ControllerFunc) {
+// OptionsHandler sets a controller as handler for OPTIONS requests to paths
+// without an explicit OPTIONS route, e.g. to answer CORS preflight requests.
+// The allowed methods are sent in the Allow header and are available via
+// Context.AllowedMethods. If the controller writes nothing the response is
+// 204 No Content.
+func (r *Router) OptionsHandler(c ControllerFunc) {
 	route := NewRoute()
 	route.Controller = c
+	route.router = r
+
+	r.kallisto.options = route
+}
+
+// serveMethodNotAllowed executes the MethodNotAllowed handler.
+func (k *Kallisto) serveMethodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	allowed := k.allowedMethods(req)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	k.serveStatus(w, req, k.methodNotAllowed, http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, AllowedMethodsKey, allowed)
+}
+
+// serveOptions executes the automatic OPTIONS handler.
+func (k *Kallisto) serveOptions(w http.ResponseWriter, req *http.Request) {
+	allowed := k.allowedMethods(req)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	k.serveStatus(w, req, k.options, http.StatusOK, http.StatusNoContent, AllowedMethodsKey, allowed)
+}
+
+// serveStatus executes the route of a NotFound, MethodNotAllowed, OPTIONS or
+// PanicHandler controller. The given key value pair is stored on the context
+// if the key is not empty.
+//
+// The default status is written if the body is written without a status code
+// and the empty status if nothing is written at all.
+func (k *Kallisto) serveStatus(w http.ResponseWriter, req *http.Request, route *Route, defaultStatus int, emptyStatus int, key string, value interface{}) {
+	ctx := k.acquireContext(w, req, route)
+	defer k.releaseContext(ctx)
+
+	if key != "" {
+		ctx.Set(key, value)
+	}
+	ctx.Response.defaultStatus = defaultStatus
+	ctx.Next()
+
+	if !ctx.Response.Written() {
+		ctx.Response.WriteHeader(emptyStatus)
+	}
+}
 
-	r.httprouter.PanicHandler = func(w http.ResponseWriter, req *http.Request, stack interface{}) {
-		ctx := newContext(r.kallisto, route)
-		ctx.Request = req
-		ctx.Response = newResponse(w, ctx)
+// allowedMethods returns the sorted methods registered for the requested path.
+// OPTIONS is always allowed for registered paths.
+func (k *Kallisto) allowedMethods(req *http.Request) []string {
+	tree, p := k.hostTree(req), k.requestPath(req)
+
+	var allowed []string
+	var ps Params
+	for _, method := range k.methods {
+		if k.match(tree, method, p, &ps) != nil {
+			allowed = append(allowed, method)
+		}
+	}
+
+	if len(allowed) > 0 && !containsString(allowed, "OPTIONS") {
+		allowed = append(allowed, "OPTIONS")
+	}
+
+	sort.Strings(allowed)
+	return allowed
+}
+
+// AllowedMethods returns the methods allowed for the requested path in the
+// MethodNotAllowed and OptionsHandler controllers.
+func (c *Context) AllowedMethods() []string {
+	allowed, _ := c.Get(AllowedMethodsKey).([]string)
+	return allowed
+}
 
-		ctx.Set("PanicStack", stack)
-		ctx.Next()
+// containsString reports whether the slice contains the string.
+func containsString(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
 	}
+	return false
+}
+
+// PanicHandler sets a controller as handler for panics which are recovered
+// while a request is handled. The recovered value is stored as "PanicStack" on the
+// context.
+//
+// The middlewares of the router are applied to the controller, so they should
+// not panic themselves. The status code defaults to 500, but the controller can
+// set another status code and headers.
+func (r *Router) PanicHandler(c ControllerFunc) {
+	route := NewRoute()
+	route.Controller = c
+	route.router = r
+
+	r.kallisto.panicHandler = route
+}
+
+// servePanic executes the PanicHandler with the recovered value.
+func (k *Kallisto) servePanic(w http.ResponseWriter, req *http.Request, stack interface{}) {
+	k.serveStatus(w, req, k.panicHandler, http.StatusInternalServerError, http.StatusInternalServerError, "PanicStack", stack)
 }
 
 // Group returns a router with the given path and name prefixes and middlewares.
 // Routes with common path or name prefixes could be registered via the group method.
+//
+// The group inherits all middlewares of the router, including those registered
+// after the group was created.
 func (r *Router) Group(pathPrefix string, namePrefix string, fn func(*Router), middlewares ...MiddlewareFunc) {
 	fn(&Router{
-		middlewares: append(r.middlewares, middlewares...),
+		middlewares: append(MiddlewareChain(nil), middlewares...),
 		kallisto:    r.kallisto,
-		httprouter:  r.httprouter,
+		parent:      r,
+		tree:        r.tree,
+		host:        r.host,
+		version:     r.version,
 		pathPrefix:  path.Join(r.pathPrefix, pathPrefix),
 		namePrefix:  r.namePrefix + namePrefix,
+
+		requirements: append([]string(nil), r.requirements...),
 	})
 }
 
 // Handle registers handlers for for the supplied path.
 //
+// Named parameters match one path segment, e.g. "/users/:id", and catch-all
+// parameters the rest of the path, e.g. "/files/*filepath". Static segments
+// take precedence over parameters, so "/users/new" and "/users/:id" can be
+// registered side by side. Parameters can be constrained by a type or a
+// regular expression, e.g. "/users/:id<int>" or "/posts/:slug<[a-z-]+>", and
+// made optional by a question mark, e.g. "/posts/:page<int>?".
+//
 // Shortcut methods are available the standard HTTP methods GET, POST, PUT, PATCH and DELETE.
+// Routes with an empty name are not added to the named routes of the application.
+//
+// Handle panics with a RouteError if the path is invalid or conflicts with a
+// registered path, as the route could not be served.
+// Routes with a duplicate name are registered, but the name keeps referring
+// to the first route and the error is reported by Kallisto.Validate.
 func (r *Router) Handle(method string, uri string, name string, controller ControllerFunc) *Route {
 	route := &Route{
+		Source:     callerSource(),
+		Method:     method,
 		Path:       path.Join(r.pathPrefix, uri),
-		Before:     r.middlewares,
-		After:      make([]MiddlewareFunc, 0),
+		Group:      r.namePrefix,
+		host:       r.host,
 		Controller: controller,
+		router:     r,
+
+		Requirements: append([]string(nil), r.requirements...),
+	}
+
+	if name != "" {
+		route.Name = r.namePrefix + name
 	}
 
-	r.kallisto.routes[r.namePrefix+name] = route
+	if r.host != nil {
+		route.Host = r.host.pattern
+	}
 
-	r.httprouter.Handle(method, path.Join(r.pathPrefix, uri), func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
-		ctx := newContext(r.kallisto, route)
-		ctx.Params = ps
-		ctx.Request = req
-		ctx.Response = newResponse(w, ctx)
+	if r.version != nil {
+		route.version = r.version
+		route.Version = r.version.Version
+	}
 
-		ctx.Next()
-	})
+	r.register(route)
 
 	return route
 }
 
+// register adds the route to the routes of the application and registers
+// it at the route tree. An invalid path or a path which conflicts with a
+// registered path panics with a RouteError. A duplicate name is collected as
+// error which is reported by Kallisto.Validate, the route is served anyway.
+func (r *Router) register(route *Route) {
+	if err := r.handle(route); err != nil {
+		panic(err)
+	}
+
+	if route.Name != "" {
+		if existing, ok := r.kallisto.routes[route.Name]; ok {
+			r.kallisto.errors = append(r.kallisto.errors, &RouteError{
+				Route:    route,
+				Existing: existing,
+				Reason:   "duplicate route name " + strconv.Quote(route.Name),
+			})
+		} else {
+			r.kallisto.routes[route.Name] = route
+		}
+	}
+	r.kallisto.routeList = append(r.kallisto.routeList, route)
+
+	if !containsString(r.kallisto.methods, route.Method) {
+		r.kallisto.methods = append(r.kallisto.methods, route.Method)
+	}
+}
+
+// handle adds the route to the route tree of its host. An invalid path or a
+// conflict with a registered path is returned as RouteError.
+func (r *Router) handle(route *Route) *RouteError {
+	segments, err := parsePath(route.Path)
+	if err != nil {
+		return &RouteError{Route: route, Reason: err.Error()}
+	}
+
+	tree := r.tree
+	if route.host != nil {
+		tree = route.host.tree
+	}
+
+	params, err := tree.add(route, segments)
+	if err != nil {
+		return &RouteError{
+			Route:    route,
+			Existing: r.kallisto.conflictingRoute(route),
+			Reason:   err.Error(),
+		}
+	}
+
+	if params > r.kallisto.maxParams {
+		r.kallisto.maxParams = params
+	}
+
+	return nil
+}
+
 // ServeHTTP is the necessary method to implement the http.Handler interface.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	r.httprouter.ServeHTTP(w, req)
+	r.kallisto.dispatch(w, req)
 }
 
 // ServeStatic registers a route to the content which should be served as static files.
+// The path must end with "/*filepath", e.g. "/public/*filepath".
 func (r *Router) ServeStatic(path string, root http.FileSystem) {
-	r.httprouter.ServeFiles(path, root)
+	if !strings.HasSuffix(path, "/*filepath") {
+		panic("path must end with /*filepath in path '" + path + "'")
+	}
+
+	fileServer := http.FileServer(root)
+	r.Handle("GET", path, "", func(c *Context, res *Response) {
+		c.Request.URL.Path = c.Param("filepath")
+		fileServer.ServeHTTP(res, c.Request)
+	})
 }
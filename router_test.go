@@ -8,6 +8,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -91,7 +92,6 @@ func TestPanicHandler(t *testing.T) {
 	s := "Panic!"
 	c := func(c *Context, r *Response) {
 		panic("stop here")
-		r.Text("should not be reached")
 	}
 	ph := func(c *Context, r *Response) {
 		r.Text(s)
@@ -149,3 +149,271 @@ func check(k *Kallisto, r *http.Request, t *testing.T, s string) {
 		t.Errorf("Body should be %s but got %s", s, w.Body.String())
 	}
 }
+
+func TestContextReleased(t *testing.T) {
+	k := New()
+	var retained *Context
+	k.GET("/", "index", func(c *Context, r *Response) {
+		c.Set("key", "value")
+		retained = c
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	k.ServeHTTP(httptest.NewRecorder(), r)
+
+	if retained.Get("key") != nil {
+		t.Errorf("Data should be reset but got %v", retained.Get("key"))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Next should panic after the request returned.")
+		}
+	}()
+	retained.Next()
+}
+
+func TestContextCopy(t *testing.T) {
+	k := New()
+	var cp *Context
+	k.GET("/", "index", func(c *Context, r *Response) {
+		c.Set("key", "value")
+		cp = c.Copy()
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	k.ServeHTTP(httptest.NewRecorder(), r)
+
+	if cp.Get("key") != "value" {
+		t.Errorf("Value should be value but got %v", cp.Get("key"))
+	}
+
+	if cp.Request != r {
+		t.Error("Copy should keep the request.")
+	}
+}
+
+func BenchmarkHandle(b *testing.B) {
+	k := New()
+	k.Use(func(c *Context) { c.Set("key", "value") })
+	k.GET("/users/:id", "user", func(c *Context, r *Response) {})
+
+	r, _ := http.NewRequest("GET", "/users/42", nil)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k.ServeHTTP(w, r)
+	}
+}
+
+func BenchmarkHandleParallel(b *testing.B) {
+	k := New()
+	k.Use(func(c *Context) { c.Set("key", "value") })
+	k.GET("/users/:id", "user", func(c *Context, r *Response) {})
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		r, _ := http.NewRequest("GET", "/users/42", nil)
+		w := httptest.NewRecorder()
+		for pb.Next() {
+			k.ServeHTTP(w, r)
+		}
+	})
+}
+
+func TestRouterAround(t *testing.T) {
+	k := New()
+	k.Around(func(c *Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				c.Response.WriteHeader(500)
+				c.Response.Text("recovered")
+			}
+		}()
+		c.Next()
+	})
+	k.GET("/", "index", func(c *Context, r *Response) { panic("stop here") })
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != 500 {
+		t.Errorf("Status should be 500 but got %d", w.Code)
+	}
+
+	if w.Body.String() != "recovered" {
+		t.Errorf("Body should be recovered but got %s", w.Body.String())
+	}
+}
+
+func TestUseAppends(t *testing.T) {
+	r := &Router{}
+	r.Use(m1)
+	r.Use(m2)
+
+	if !compareMiddlewareFunc(r.middlewares, MiddlewareChain{m1, m2}) {
+		t.Error("Middlewares do not match.")
+	}
+}
+
+func TestUseAfter(t *testing.T) {
+	k := New()
+	s := ""
+	c := func(c *Context, r *Response) { s += "controller" }
+
+	k.UseAfter(func(c *Context) { s += ":app" })
+	k.Group("/group", "group::", func(r *Router) {
+		r.UseAfter(func(c *Context) { s += ":group" })
+		r.GET("/get", "get", c).SetAfter(func(c *Context) { s += ":route" })
+	})
+
+	r, _ := http.NewRequest("GET", "/group/get", nil)
+	k.ServeHTTP(httptest.NewRecorder(), r)
+
+	if s != "controller:route:group:app" {
+		t.Errorf("Result should be controller:route:group:app but got %s", s)
+	}
+}
+
+func TestUseAfterRoutes(t *testing.T) {
+	k := New()
+	s := ""
+	c := func(c *Context, r *Response) { r.Text(s) }
+
+	k.Group("/group", "group::", func(r *Router) {
+		r.GET("/get", "get", c)
+	})
+	k.Use(func(c *Context) { s = "first" })
+
+	r, _ := http.NewRequest("GET", "/group/get", nil)
+	check(k, r, t, "first")
+
+	k.Use(func(c *Context) { s += ":second" })
+	check(k, r, t, "first:second")
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	k := New()
+	c := func(c *Context, r *Response) {}
+	k.GET("/users", "users::index", c)
+	k.POST("/users", "users::create", c)
+
+	r, _ := http.NewRequest("DELETE", "/users", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != 405 {
+		t.Errorf("Status should be 405 but got %d", w.Code)
+	}
+
+	if w.Header().Get("Allow") != "GET, OPTIONS, POST" {
+		t.Errorf("Allow should be GET, OPTIONS, POST but got %s", w.Header().Get("Allow"))
+	}
+
+	k.Use(func(c *Context) { c.Response.Header().Set("Custom-Attr", "test") })
+	k.MethodNotAllowed(func(c *Context, r *Response) {
+		r.Text("allowed: ", strings.Join(c.AllowedMethods(), " "))
+	})
+
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != 405 {
+		t.Errorf("Status should be 405 but got %d", w.Code)
+	}
+
+	if w.Body.String() != "allowed: GET OPTIONS POST" {
+		t.Errorf("Body should be allowed: GET OPTIONS POST but got %s", w.Body.String())
+	}
+
+	if w.Header().Get("Custom-Attr") != "test" {
+		t.Errorf("Header Custom-Attr should be test but got %s", w.Header().Get("Custom-Attr"))
+	}
+}
+
+func TestOptions(t *testing.T) {
+	k := New()
+	k.Use(func(c *Context) { c.Response.Header().Set("Custom-Attr", "test") })
+	k.GET("/users", "users::index", func(c *Context, r *Response) {})
+	k.OPTIONS("/explicit", "explicit", func(c *Context, r *Response) { r.Text("explicit") })
+
+	r, _ := http.NewRequest("OPTIONS", "/users", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != 204 {
+		t.Errorf("Status should be 204 but got %d", w.Code)
+	}
+
+	if w.Header().Get("Allow") != "GET, OPTIONS" {
+		t.Errorf("Allow should be GET, OPTIONS but got %s", w.Header().Get("Allow"))
+	}
+
+	if w.Header().Get("Custom-Attr") != "test" {
+		t.Errorf("Header Custom-Attr should be test but got %s", w.Header().Get("Custom-Attr"))
+	}
+
+	k.OptionsHandler(func(c *Context, r *Response) {
+		r.Header().Set("Access-Control-Allow-Methods", strings.Join(c.AllowedMethods(), ", "))
+	})
+
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Header().Get("Access-Control-Allow-Methods") != "GET, OPTIONS" {
+		t.Errorf("Access-Control-Allow-Methods should be GET, OPTIONS but got %s", w.Header().Get("Access-Control-Allow-Methods"))
+	}
+
+	r, _ = http.NewRequest("OPTIONS", "/explicit", nil)
+	check(k, r, t, "explicit")
+}
+
+func TestErrorRoutesMiddlewares(t *testing.T) {
+	k := New()
+	k.Use(func(c *Context) { c.Response.Header().Set("Custom-Attr", "test") })
+	k.NotFound(func(c *Context, r *Response) {
+		r.Header().Set("Cache-Control", "no-store")
+		r.WriteHeader(410)
+		r.Text("gone")
+	})
+	k.PanicHandler(func(c *Context, r *Response) { r.Text("panic") })
+	k.GET("/panic", "panic", func(c *Context, r *Response) { panic("stop here") })
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/missing", 410, "gone"},
+		{"/panic", 500, "panic"},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", test.path, nil)
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("Status should be %d but got %d", test.status, w.Code)
+		}
+
+		if w.Body.String() != test.body {
+			t.Errorf("Body should be %s but got %s", test.body, w.Body.String())
+		}
+
+		if w.Header().Get("Custom-Attr") != "test" {
+			t.Errorf("Header Custom-Attr should be test but got %s", w.Header().Get("Custom-Attr"))
+		}
+	}
+
+	r, _ := http.NewRequest("GET", "/missing", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Header Cache-Control should be no-store but got %s", w.Header().Get("Cache-Control"))
+	}
+}
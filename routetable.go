@@ -0,0 +1,99 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteList returns all registered routes, including unnamed routes, sorted
+// by path, host and method.
+func (k *Kallisto) RouteList() []*Route {
+	routes := make([]*Route, len(k.routeList))
+	copy(routes, k.routeList)
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes
+}
+
+// MiddlewareNames returns the function names of all middlewares applied to the
+// route in the order of their execution, including those of its routers.
+func (r *Route) MiddlewareNames() []string {
+	controller := reflect.ValueOf(callController).Pointer()
+
+	var names []string
+	for _, m := range r.handlers() {
+		pc := reflect.ValueOf(m).Pointer()
+		if pc == controller {
+			continue
+		}
+		names = append(names, funcName(pc))
+	}
+	return names
+}
+
+// PrintRoutes writes a table of all registered routes to the given writer.
+// The paths of routes of host groups are prefixed by their host pattern.
+func (k *Kallisto) PrintRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tNAME\tREQUIRES\tMIDDLEWARES")
+
+	for _, route := range k.RouteList() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			route.Method,
+			route.Host+route.Path,
+			orDash(route.Name),
+			orDash(strings.Join(route.Requirements, ",")),
+			orDash(strings.Join(route.MiddlewareNames(), ",")),
+		)
+	}
+
+	return tw.Flush()
+}
+
+// RouteTable is a controller which responds with the route table of the
+// application. It is meant for debugging and should not be exposed publicly,
+// e.g. k.GET("/debug/routes", "debug::routes", kallisto.RouteTable).
+func RouteTable(c *Context, r *Response) {
+	r.Header().Set("Content-Type", "text/plain")
+	c.App().PrintRoutes(r)
+}
+
+// funcName returns the name of the function at the given program counter
+// without its package path.
+func funcName(pc uintptr) string {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// orDash returns the string or "-" if it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
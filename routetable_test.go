@@ -0,0 +1,84 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func logger(c *Context) {}
+
+func TestRouteList(t *testing.T) {
+	k := New()
+	c := func(c *Context, r *Response) {}
+
+	k.POST("/users", "users::create", c)
+	k.GET("/users", "users::index", c)
+	k.Group("/admin", "admin::", func(r *Router) {
+		r.GET("/", "index", c).SetMeta("description", "Dashboard")
+	})
+
+	var routes []string
+	for _, route := range k.RouteList() {
+		routes = append(routes, route.Method+" "+route.Path+" "+route.Name)
+	}
+
+	expected := []string{"GET /admin admin::index", "GET /users users::index", "POST /users users::create"}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Routes should be %v but got %v", expected, routes)
+	}
+
+	route := k.Routes()["admin::index"]
+	if route.Group != "admin::" {
+		t.Errorf("Group should be admin:: but got %s", route.Group)
+	}
+
+	if route.Meta["description"] != "Dashboard" {
+		t.Errorf("Meta description should be Dashboard but got %v", route.Meta["description"])
+	}
+}
+
+func TestMiddlewareNames(t *testing.T) {
+	k := New()
+	k.Use(logger)
+	route := k.GET("/", "index", func(c *Context, r *Response) {})
+
+	names := []string{"kallisto.logger"}
+	if !reflect.DeepEqual(route.MiddlewareNames(), names) {
+		t.Errorf("Middleware names should be %v but got %v", names, route.MiddlewareNames())
+	}
+}
+
+func TestPrintRoutes(t *testing.T) {
+	k := New()
+	k.Use(logger)
+	k.GET("/users/:id", "users::show", func(c *Context, r *Response) {}).Require("admin")
+	k.GET("/debug/routes", "debug::routes", RouteTable)
+
+	var buf bytes.Buffer
+	k.PrintRoutes(&buf)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Route table should have 3 lines but got %d", len(lines))
+	}
+
+	if strings.Join(strings.Fields(lines[2]), " ") != "GET /users/:id users::show admin kallisto.logger" {
+		t.Errorf("Unexpected route table line %s", lines[2])
+	}
+
+	r, _ := http.NewRequest("GET", "/debug/routes", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Body.String() != buf.String() {
+		t.Errorf("Body should be %s but got %s", buf.String(), w.Body.String())
+	}
+}
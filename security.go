@@ -0,0 +1,130 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NonceKey is the context key under which the SecurityHeaders middleware stores
+// the Content-Security-Policy nonce of a request. As it is part of the context
+// data it is available in templates rendered via Response.HTML, e.g.
+// <script nonce="{{.CSPNonce}}">.
+const NonceKey = "CSPNonce"
+
+// NoncePlaceholder is replaced by the nonce of the request in the
+// ContentSecurityPolicy of a SecurityConfig.
+const NoncePlaceholder = "{nonce}"
+
+// SecurityConfig configures the headers set by the SecurityHeaders middleware.
+// Empty values are not sent.
+type SecurityConfig struct {
+	// HSTSMaxAge is the max-age of the Strict-Transport-Security header.
+	HSTSMaxAge time.Duration
+
+	// HSTSIncludeSubdomains adds the includeSubDomains directive.
+	HSTSIncludeSubdomains bool
+
+	// HSTSPreload adds the preload directive.
+	HSTSPreload bool
+
+	// ContentTypeNosniff sets X-Content-Type-Options to nosniff.
+	ContentTypeNosniff bool
+
+	// FrameOptions is the value of the X-Frame-Options header.
+	FrameOptions string
+
+	// ReferrerPolicy is the value of the Referrer-Policy header.
+	ReferrerPolicy string
+
+	// PermissionsPolicy is the value of the Permissions-Policy header.
+	PermissionsPolicy string
+
+	// ContentSecurityPolicy is the value of the Content-Security-Policy header.
+	// If it contains NoncePlaceholder a new nonce is generated for every request,
+	// e.g. "script-src 'self' 'nonce-{nonce}'".
+	ContentSecurityPolicy string
+}
+
+// DefaultSecurityConfig returns a restrictive configuration which allows
+// scripts from the same origin and inline scripts with the request nonce.
+func DefaultSecurityConfig() SecurityConfig {
+	return SecurityConfig{
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		ContentTypeNosniff:    true,
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		PermissionsPolicy:     "camera=(), microphone=(), geolocation=()",
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'nonce-" + NoncePlaceholder + "'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+	}
+}
+
+// SecurityHeaders returns a middleware which sets the security related
+// response headers of the given configuration.
+func SecurityHeaders(config SecurityConfig) MiddlewareFunc {
+	var hsts string
+	if config.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(config.HSTSMaxAge/time.Second), 10)
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	useNonce := strings.Contains(config.ContentSecurityPolicy, NoncePlaceholder)
+
+	return func(c *Context) {
+		h := c.Response.Header()
+
+		if hsts != "" {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		if config.ContentTypeNosniff {
+			h.Set("X-Content-Type-Options", "nosniff")
+		}
+		if config.FrameOptions != "" {
+			h.Set("X-Frame-Options", config.FrameOptions)
+		}
+		if config.ReferrerPolicy != "" {
+			h.Set("Referrer-Policy", config.ReferrerPolicy)
+		}
+		if config.PermissionsPolicy != "" {
+			h.Set("Permissions-Policy", config.PermissionsPolicy)
+		}
+
+		csp := config.ContentSecurityPolicy
+		if useNonce {
+			nonce := newNonce()
+			c.Set(NonceKey, nonce)
+			csp = strings.Replace(csp, NoncePlaceholder, nonce, -1)
+		}
+		if csp != "" {
+			h.Set("Content-Security-Policy", csp)
+		}
+	}
+}
+
+// Nonce returns the Content-Security-Policy nonce of the request or an empty
+// string if no nonce was generated.
+func (c *Context) Nonce() string {
+	nonce, _ := c.Get(NonceKey).(string)
+	return nonce
+}
+
+// newNonce returns a base64 encoded random value with 128 bits of entropy.
+func newNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
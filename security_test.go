@@ -0,0 +1,91 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type nonceRenderer struct{}
+
+func (r nonceRenderer) Render(w io.Writer, v interface{}, t []string) {
+	fmt.Fprintf(w, `<script nonce="%s"></script>`, v.(Data)[NonceKey])
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	k := New()
+	k.Use(SecurityHeaders(DefaultSecurityConfig()))
+
+	var nonce string
+	k.GET("/", "index", func(c *Context, r *Response) {
+		nonce = c.Nonce()
+		r.SetRenderer(nonceRenderer{})
+		r.HTML(nil, "index.html")
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	headers := map[string]string{
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+	}
+
+	for key, value := range headers {
+		if w.Header().Get(key) != value {
+			t.Errorf("Header %s should be %s but got %s", key, value, w.Header().Get(key))
+		}
+	}
+
+	if nonce == "" {
+		t.Fatal("Nonce should not be empty.")
+	}
+
+	if !strings.Contains(w.Header().Get("Content-Security-Policy"), "'nonce-"+nonce+"'") {
+		t.Errorf("Content-Security-Policy should contain the nonce %s but got %s", nonce, w.Header().Get("Content-Security-Policy"))
+	}
+
+	body := `<script nonce="` + nonce + `"></script>`
+	if w.Body.String() != body {
+		t.Errorf("Body should be %s but got %s", body, w.Body.String())
+	}
+
+	first := nonce
+	k.ServeHTTP(httptest.NewRecorder(), r)
+
+	if nonce == first {
+		t.Error("Nonce should differ between requests.")
+	}
+}
+
+func TestSecurityHeadersWithoutNonce(t *testing.T) {
+	k := New()
+	k.Use(SecurityHeaders(SecurityConfig{ContentSecurityPolicy: "default-src 'self'"}))
+	k.GET("/", "index", func(c *Context, r *Response) { r.Text(c.Nonce()) })
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Header().Get("Strict-Transport-Security") != "" {
+		t.Errorf("Strict-Transport-Security should be empty but got %s", w.Header().Get("Strict-Transport-Security"))
+	}
+
+	if w.Header().Get("Content-Security-Policy") != "default-src 'self'" {
+		t.Errorf("Content-Security-Policy should be default-src 'self' but got %s", w.Header().Get("Content-Security-Policy"))
+	}
+
+	if w.Body.String() != "" {
+		t.Errorf("Nonce should be empty but got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrStreamingUnsupported is returned by Response.SSE if the response writer
+// can not be flushed.
+var ErrStreamingUnsupported = errors.New("kallisto: response writer does not support flushing")
+
+// An Event is a server-sent event.
+type Event struct {
+	// ID is the id of the event which the client sends as Last-Event-ID
+	// header when it reconnects.
+	ID string
+
+	// Event is the type of the event. Clients receive events without a type
+	// as "message" events.
+	Event string
+
+	// Data is the payload of the event. Strings and byte slices are sent as
+	// they are, everything else is encoded as JSON.
+	Data interface{}
+
+	// Retry is the reconnection time the client should use. It is omitted if
+	// it is zero.
+	Retry time.Duration
+}
+
+// An EventStream sends server-sent events to the client.
+type EventStream struct {
+	// LastEventID is the id of the last event the client received before it
+	// reconnected, as sent in the Last-Event-ID header.
+	LastEventID string
+
+	r  *Response
+	rc *http.ResponseController
+	mu sync.Mutex
+}
+
+// SSE starts a stream of server-sent events. It sends the event stream headers
+// and flushes them, so the client is connected immediately. Response writers
+// wrapping another one, e.g. installed by WrapMiddleware, are unwrapped by
+// their Unwrap method like by http.ResponseController.
+func (r *Response) SSE() (*EventStream, error) {
+	if !canFlush(r.ResponseWriter) {
+		return nil, ErrStreamingUnsupported
+	}
+
+	h := r.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	r.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(r.ResponseWriter)
+	if err := rc.Flush(); err != nil {
+		return nil, err
+	}
+
+	return &EventStream{
+		LastEventID: r.ctx.Request.Header.Get("Last-Event-ID"),
+		r:           r,
+		rc:          rc,
+	}, nil
+}
+
+// canFlush reports whether the response writer or one of the writers it wraps
+// can be flushed.
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case http.Flusher, interface{ FlushError() error }:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// Send writes the event to the client and flushes it. It returns the error of
+// the request context if the client disconnected.
+func (s *EventStream) Send(e Event) error {
+	data, err := eventData(e.Data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + singleLine(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + singleLine(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	// EventSource treats CRLF, LF and a lone CR as line breaks.
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Heartbeat writes a comment to keep the connection open through proxies and
+// to detect disconnected clients.
+func (s *EventStream) Heartbeat() error {
+	return s.write(": heartbeat\n\n")
+}
+
+// Done returns a channel that is closed when the client disconnected.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.r.ctx.Done()
+}
+
+// Stream sends the events of the channel until it is closed or the client
+// disconnects. A heartbeat is sent after each interval without events unless
+// the interval is zero. It returns nil if the channel was closed.
+func (s *EventStream) Stream(events <-chan Event, heartbeat time.Duration) error {
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.Done():
+			return s.r.ctx.Err()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := s.Send(e); err != nil {
+				return err
+			}
+		case <-tick:
+			if err := s.Heartbeat(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// write writes and flushes the string unless the client disconnected.
+func (s *EventStream) write(str string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.r.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.r.Write([]byte(str)); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// eventData returns the data of an event as string.
+func eventData(data interface{}) (string, error) {
+	switch d := data.(type) {
+	case string:
+		return d, nil
+	case []byte:
+		return string(d), nil
+	}
+
+	b, err := json.Marshal(data)
+	return string(b), err
+}
+
+// singleLine removes line breaks from an event field.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// A Broker is a service which broadcasts events to all subscribed event
+// streams. It must be registered with Kallisto.SetService, so it is started
+// with the application, e.g.
+//
+//	broker := kallisto.NewBroker(100)
+//	k.SetService("events", broker)
+//	k.GET("/events", "events", broker.Stream)
+//
+// The broker keeps the latest events, so clients which reconnect with a
+// Last-Event-ID header receive the events they missed.
+type Broker struct {
+	// Heartbeat is the interval of heartbeats sent by Stream. It defaults
+	// to 15 seconds.
+	Heartbeat time.Duration
+
+	history     int
+	events      []Event
+	nextID      uint64
+	subscribers map[*subscriber]struct{}
+
+	publish     chan Event
+	subscribe   chan *subscriber
+	unsubscribe chan *subscriber
+}
+
+// subscriber is an event stream subscribed to a broker.
+type subscriber struct {
+	lastEventID string
+	events      chan Event
+}
+
+// NewBroker returns a broker which keeps the given number of events for
+// clients which reconnect.
+func NewBroker(history int) *Broker {
+	return &Broker{
+		Heartbeat:   15 * time.Second,
+		history:     history,
+		subscribers: make(map[*subscriber]struct{}),
+		publish:     make(chan Event),
+		subscribe:   make(chan *subscriber),
+		unsubscribe: make(chan *subscriber),
+	}
+}
+
+// Run implements the Runner interface. It distributes the published events
+// to the subscribers. Subscribers which do not keep up are disconnected.
+func (b *Broker) Run() {
+	for {
+		select {
+		case s := <-b.subscribe:
+			if s.lastEventID != "" {
+				for i, e := range b.events {
+					if e.ID == s.lastEventID {
+						for _, missed := range b.events[i+1:] {
+							s.events <- missed
+						}
+						break
+					}
+				}
+			}
+			b.subscribers[s] = struct{}{}
+
+		case s := <-b.unsubscribe:
+			if _, ok := b.subscribers[s]; ok {
+				delete(b.subscribers, s)
+				close(s.events)
+			}
+
+		case e := <-b.publish:
+			if e.ID == "" {
+				b.nextID++
+				e.ID = strconv.FormatUint(b.nextID, 10)
+			}
+			if b.history > 0 {
+				if len(b.events) == b.history {
+					b.events = append(b.events[:0], b.events[1:]...)
+				}
+				b.events = append(b.events, e)
+			}
+
+			for s := range b.subscribers {
+				select {
+				case s.events <- e:
+				default:
+					delete(b.subscribers, s)
+					close(s.events)
+				}
+			}
+		}
+	}
+}
+
+// Publish broadcasts the event to all subscribers. Events without an id are
+// numbered consecutively.
+func (b *Broker) Publish(e Event) {
+	b.publish <- e
+}
+
+// Subscribe returns a channel which receives all events published from now on,
+// preceded by the kept events after the given last event id. The channel is
+// closed after the returned cancel function was called or if the subscriber
+// does not keep up with the published events.
+func (b *Broker) Subscribe(lastEventID string) (<-chan Event, func()) {
+	s := &subscriber{lastEventID: lastEventID, events: make(chan Event, b.history+16)}
+	b.subscribe <- s
+
+	var once sync.Once
+	return s.events, func() {
+		once.Do(func() { b.unsubscribe <- s })
+	}
+}
+
+// Stream is a controller which streams the events of the broker to the client,
+// starting after the event identified by the Last-Event-ID header.
+func (b *Broker) Stream(c *Context, r *Response) {
+	stream, err := r.SSE()
+	if err != nil {
+		http.Error(r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	events, cancel := b.Subscribe(stream.LastEventID)
+	defer cancel()
+
+	stream.Stream(events, b.Heartbeat)
+}
@@ -0,0 +1,187 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSE(t *testing.T) {
+	k := New()
+	k.GET("/events", "events", func(c *Context, r *Response) {
+		stream, err := r.SSE()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		events := make(chan Event, 4)
+		events <- Event{ID: "1", Event: "update", Data: "first\nsecond", Retry: 3 * time.Second}
+		events <- Event{Data: map[string]int{"count": 2}}
+		events <- Event{ID: "3\n", Data: []byte("bytes")}
+		events <- Event{Data: "x\rid: 99\r\nevent: admin"}
+		close(events)
+
+		if err := stream.Stream(events, 0); err != nil {
+			t.Errorf("Stream should return nil but got %v", err)
+		}
+	})
+
+	r, _ := http.NewRequest("GET", "/events", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Content-Type should be text/event-stream but got %s", w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("Cache-Control should be no-cache but got %s", w.Header().Get("Cache-Control"))
+	}
+	if !w.Flushed {
+		t.Error("Response should be flushed.")
+	}
+
+	body := "id: 1\nevent: update\nretry: 3000\ndata: first\ndata: second\n\n" +
+		"data: {\"count\":2}\n\n" +
+		"id: 3\ndata: bytes\n\n" +
+		"data: x\ndata: id: 99\ndata: event: admin\n\n"
+	if w.Body.String() != body {
+		t.Errorf("Body should be %q but got %q", body, w.Body.String())
+	}
+}
+
+// unwrapWriter wraps a response writer without implementing http.Flusher.
+type unwrapWriter struct {
+	http.ResponseWriter
+}
+
+func (w unwrapWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestSSEWrappedWriter(t *testing.T) {
+	controller := func(c *Context, r *Response) {
+		stream, err := r.SSE()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream.Send(Event{Data: "wrapped"})
+	}
+
+	k := New()
+	k.GET("/timeout", "timeout", controller).SetBefore(Timeout(time.Second))
+	k.GET("/wrapped", "wrapped", controller).SetBefore(WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(unwrapWriter{w}, r)
+		})
+	}))
+
+	for _, p := range []string{"/timeout", "/wrapped"} {
+		r, _ := http.NewRequest("GET", p, nil)
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if !w.Flushed {
+			t.Errorf("Response of %s should be flushed.", p)
+		}
+		if w.Body.String() != "data: wrapped\n\n" {
+			t.Errorf("Body of %s should be %q but got %q", p, "data: wrapped\n\n", w.Body.String())
+		}
+	}
+
+	k.GET("/unsupported", "unsupported", func(c *Context, r *Response) {
+		if _, err := r.SSE(); err != ErrStreamingUnsupported {
+			t.Errorf("Error should be %v but got %v", ErrStreamingUnsupported, err)
+		}
+	}).SetBefore(WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(struct{ http.ResponseWriter }{w}, r)
+		})
+	}))
+
+	r, _ := http.NewRequest("GET", "/unsupported", nil)
+	k.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestSSEHeartbeatAndDisconnect(t *testing.T) {
+	done := make(chan error, 1)
+
+	k := New()
+	k.GET("/events", "events", func(c *Context, r *Response) {
+		stream, _ := r.SSE()
+		done <- stream.Stream(make(chan Event), 10*time.Millisecond)
+	})
+
+	server := httptest.NewServer(k)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line, _ := bufio.NewReader(res.Body).ReadString('\n')
+	if line != ": heartbeat\n" {
+		t.Errorf("Line should be a heartbeat but got %q", line)
+	}
+	res.Body.Close()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Stream should return an error after the client disconnected.")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Stream should return after the client disconnected.")
+	}
+}
+
+func TestBroker(t *testing.T) {
+	broker := NewBroker(10)
+	broker.Heartbeat = time.Hour
+
+	k := New()
+	k.SetService("events", broker)
+	k.GET("/events", "events", broker.Stream)
+	k.StartServices()
+
+	server := httptest.NewServer(k)
+	defer server.Close()
+
+	broker.Publish(Event{Data: "one"})
+	broker.Publish(Event{Data: "two"})
+
+	// A client which reconnects after the first event receives the second.
+	req, _ := http.NewRequest("GET", server.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	broker.Publish(Event{Event: "news", Data: "three"})
+
+	reader := bufio.NewReader(res.Body)
+	var lines []string
+	for len(lines) < 5 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	expected := "id: 2,data: two,id: 3,event: news,data: three"
+	if strings.Join(lines, ",") != expected {
+		t.Errorf("Events should be %s but got %s", expected, strings.Join(lines, ","))
+	}
+}
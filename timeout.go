@@ -0,0 +1,195 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout returns a middleware which limits the execution time of the remaining
+// middlewares and the controller to the given duration and replies with 503
+// Service Unavailable if it is exceeded.
+//
+// The deadline is set on the request context and therefore on the Context
+// itself. Handlers should stop working once it is done. Everything
+// written to the response after the deadline is discarded.
+//
+// The response is buffered until the handler finished, unless it flushes the
+// response or hijacks the connection. From then on it is sent directly and
+// the timeout only cancels the request context, so streaming handlers should
+// stop once it is done.
+//
+// The duration can be overridden per route with Route.SetTimeout.
+func Timeout(d time.Duration) MiddlewareFunc {
+	return TimeoutStatus(d, http.StatusServiceUnavailable)
+}
+
+// TimeoutStatus is like Timeout but replies with the given status code,
+// e.g. 504 Gateway Timeout.
+func TimeoutStatus(d time.Duration, code int) MiddlewareFunc {
+	return func(c *Context) {
+		timeout := d
+		if c.route != nil && c.route.Timeout > 0 {
+			timeout = c.route.Timeout
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		// The remaining chain runs on a copy of the context with a buffered
+		// response, so nothing touches the original after the deadline.
+		tw := &timeoutWriter{ctx: ctx, w: c.Response, header: make(http.Header)}
+		tc := *c
+		tc.Request = c.Request.WithContext(ctx)
+		tc.Response = newResponse(tw, &tc)
+		tc.Response.Renderer = c.Response.Renderer
+		tc.Response.defaultStatus = c.Response.defaultStatus
+
+		done := make(chan struct{})
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			tc.Next()
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			// The chain was completed by the copy.
+			c.index, c.aborted = tc.index, tc.aborted
+
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.commit()
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.timedOut = true
+			// The controller may still use the data of the context.
+			c.retained = true
+			c.Abort()
+			if !tw.committed {
+				http.Error(c.Response, http.StatusText(code), code)
+			}
+		}
+	}
+}
+
+// timeoutWriter buffers a response until the handler finished in time or
+// commits it to the original response.
+type timeoutWriter struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	w         *Response
+	header    http.Header
+	body      []byte
+	code      int
+	committed bool
+	timedOut  bool
+}
+
+// commit writes the buffered header and body to the original response. Later
+// writes are passed through. The status code is only written if the handler
+// wrote one, so the caller can still write its own. The caller must hold the
+// lock.
+func (tw *timeoutWriter) commit() {
+	if tw.committed {
+		return
+	}
+	tw.committed = true
+
+	for k, v := range tw.header {
+		tw.w.Header()[k] = v
+	}
+	if tw.code != 0 {
+		tw.w.WriteHeader(tw.code)
+	}
+	if len(tw.body) > 0 {
+		tw.w.Write(tw.body)
+	}
+	tw.body = nil
+}
+
+// Header implements the http.ResponseWriter interface.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write implements the http.ResponseWriter interface.
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.committed {
+		// The deadline may be exceeded before the middleware noticed it.
+		if tw.ctx.Err() != nil {
+			return 0, http.ErrHandlerTimeout
+		}
+		return tw.w.Write(b)
+	}
+	if tw.code == 0 {
+		tw.code = tw.w.defaultStatus
+	}
+	tw.body = append(tw.body, b...)
+	return len(b), nil
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
+
+// Flush implements the http.Flusher interface. It commits the response, so
+// the timeout status code can not be sent anymore.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.commit()
+	if !tw.w.Written() {
+		tw.w.WriteHeader(tw.w.defaultStatus)
+	}
+	http.NewResponseController(tw.w.ResponseWriter).Flush()
+}
+
+// Hijack implements the http.Hijacker interface if the original response
+// writer supports it. Nothing must have been written before.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	if tw.committed || tw.code != 0 {
+		return nil, nil, errors.New("kallisto: response was written before the connection was hijacked")
+	}
+
+	conn, rw, err := http.NewResponseController(tw.w.ResponseWriter).Hijack()
+	if err == nil {
+		tw.committed = true
+	}
+	return conn, rw, err
+}
@@ -0,0 +1,201 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	k := New()
+	k.Use(Timeout(time.Second))
+
+	served := make(chan struct{})
+	late := make(chan error, 1)
+	k.GET("/slow", "slow", func(c *Context, r *Response) {
+		<-served
+		if c.Context().Err() == nil {
+			t.Error("Request context should be done.")
+		}
+		_, err := r.Write([]byte("too late"))
+		late <- err
+	}).SetTimeout(10 * time.Millisecond)
+
+	r, _ := http.NewRequest("GET", "/slow", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	close(served)
+
+	if w.Code != 503 {
+		t.Errorf("Status should be 503 but got %d", w.Code)
+	}
+
+	if err := <-late; err != http.ErrHandlerTimeout {
+		t.Errorf("Error should be %v but got %v", http.ErrHandlerTimeout, err)
+	}
+
+	if w.Body.String() != "Service Unavailable\n" {
+		t.Errorf("Body should be Service Unavailable but got %s", w.Body.String())
+	}
+}
+
+func TestTimeoutNotExceeded(t *testing.T) {
+	k := New()
+	after := false
+
+	k.GET("/fast", "fast", func(c *Context, r *Response) {
+		if _, ok := c.Context().Deadline(); !ok {
+			t.Error("Request context should have a deadline.")
+		}
+		r.Header().Set("Custom-Attr", "test")
+		r.WriteHeader(201)
+		r.Text("fast")
+	}).SetBefore(TimeoutStatus(time.Second, 504)).SetAfter(func(c *Context) { after = true })
+
+	r, _ := http.NewRequest("GET", "/fast", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != 201 {
+		t.Errorf("Status should be 201 but got %d", w.Code)
+	}
+
+	if w.Header().Get("Custom-Attr") != "test" {
+		t.Errorf("Header Custom-Attr should be test but got %s", w.Header().Get("Custom-Attr"))
+	}
+
+	if w.Body.String() != "fast" {
+		t.Errorf("Body should be fast but got %s", w.Body.String())
+	}
+
+	if !after {
+		t.Error("After middlewares should be called.")
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	k := New()
+	k.Use(Timeout(time.Second))
+	k.PanicHandler(func(c *Context, r *Response) { r.Text("recovered") })
+	k.GET("/", "index", func(c *Context, r *Response) { panic("stop here") })
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Status should be 500 but got %d", w.Code)
+	}
+
+	if w.Body.String() != "recovered" {
+		t.Errorf("Body should be recovered but got %s", w.Body.String())
+	}
+}
+
+func TestTimeoutNotFound(t *testing.T) {
+	k := New()
+	k.Use(Timeout(time.Second))
+	k.NotFound(func(c *Context, r *Response) { r.Text("missing") })
+
+	r, _ := http.NewRequest("GET", "/missing", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status should be 404 but got %d", w.Code)
+	}
+
+	if w.Body.String() != "missing" {
+		t.Errorf("Body should be missing but got %s", w.Body.String())
+	}
+}
+
+func TestTimeoutFlush(t *testing.T) {
+	k := New()
+	k.Use(Timeout(time.Second))
+
+	late := make(chan error, 1)
+	k.GET("/stream", "stream", func(c *Context, r *Response) {
+		r.Header().Set("Content-Type", "text/plain")
+		r.Write([]byte("first"))
+		if err := http.NewResponseController(r.ResponseWriter).Flush(); err != nil {
+			t.Errorf("Flush should succeed but got %v", err)
+		}
+		r.Write([]byte(" second"))
+
+		<-c.Context().Done()
+		_, err := r.Write([]byte(" too late"))
+		late <- err
+	}).SetTimeout(10 * time.Millisecond)
+
+	r, _ := http.NewRequest("GET", "/stream", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if err := <-late; err != http.ErrHandlerTimeout {
+		t.Errorf("Error should be %v but got %v", http.ErrHandlerTimeout, err)
+	}
+
+	if w.Code != 200 {
+		t.Errorf("Status should be 200 but got %d", w.Code)
+	}
+
+	if !w.Flushed {
+		t.Error("Response should be flushed.")
+	}
+
+	if w.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("Content-Type should be text/plain but got %s", w.Header().Get("Content-Type"))
+	}
+
+	if w.Body.String() != "first second" {
+		t.Errorf("Body should be first second but got %s", w.Body.String())
+	}
+}
+
+func TestTimeoutHijack(t *testing.T) {
+	k := New()
+	k.Use(Timeout(time.Second))
+	k.GET("/", "index", func(c *Context, r *Response) {
+		_, _, err := http.NewResponseController(r.ResponseWriter).Hijack()
+		if err == nil {
+			t.Error("Hijack should fail for a response recorder.")
+		}
+		r.Text("not hijacked")
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	check(k, r, t, "not hijacked")
+}
+
+func TestTimeoutMethodNotAllowed(t *testing.T) {
+	k := New()
+	k.Use(Timeout(time.Second))
+	k.GET("/users", "users", func(c *Context, r *Response) {})
+
+	tests := []struct {
+		method string
+		status int
+	}{
+		{"POST", http.StatusMethodNotAllowed},
+		{"OPTIONS", http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest(test.method, "/users", nil)
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("Status for %s should be %d but got %d", test.method, test.status, w.Code)
+		}
+		if w.Header().Get("Allow") != "GET, OPTIONS" {
+			t.Errorf("Allow for %s should be GET, OPTIONS but got %s", test.method, w.Header().Get("Allow"))
+		}
+	}
+}
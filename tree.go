@@ -0,0 +1,360 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Param is a single route parameter, consisting of a key and a value.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params is a list of route parameters in the order of their appearance in
+// the route path.
+type Params []Param
+
+// ByName returns the value of the first parameter with the given key or an
+// empty string if there is no such parameter.
+func (ps Params) ByName(name string) string {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// tree is a radix tree of the routes of a host with one root node per method.
+type tree struct {
+	methods []string
+	roots   []*node
+}
+
+// node is a node of a tree. The path of the node is matched first, followed
+// by its static children, its parameters in the order of their registration
+// with constrained parameters first and finally its catch-all parameter.
+type node struct {
+	// path is the static part of the path matched by the node.
+	path string
+
+	// indices holds the first bytes of the paths of the static children.
+	indices string
+
+	// static are the children with a static path.
+	static []*node
+
+	// params are the children matching a named parameter.
+	params []*node
+
+	// catchAll is the child matching the remaining path.
+	catchAll *node
+
+	// name is the name of a parameter node.
+	name string
+
+	// expr is the constraint of a parameter node as registered.
+	expr string
+
+	// constraint restricts the values of a parameter node.
+	constraint *regexp.Regexp
+
+	// route is the route registered for the path of the node.
+	route *Route
+}
+
+// root returns the root node of the given method.
+func (t *tree) root(method string) *node {
+	for i, m := range t.methods {
+		if m == method {
+			return t.roots[i]
+		}
+	}
+	return nil
+}
+
+// add registers the route under the path of the given segments. It returns
+// the number of parameters of the path or an error if the path conflicts with
+// a registered path.
+func (t *tree) add(route *Route, segments []segment) (int, error) {
+	variants := expandOptional(segments)
+
+	// All variants are checked before the tree is changed, so a conflicting
+	// route is not registered under some of its paths.
+	root := t.root(route.Method)
+	if root != nil {
+		for _, variant := range variants {
+			if err := root.conflict(route, variant); err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		root = &node{}
+		t.methods = append(t.methods, route.Method)
+		t.roots = append(t.roots, root)
+	}
+
+	var err error
+	maxParams := 0
+	for _, variant := range variants {
+		n, static, params := root, "", 0
+		for _, s := range variant {
+			switch s.kind {
+			case staticSegment:
+				static += "/" + s.value
+				continue
+			case paramSegment:
+				if n, err = n.addStatic(static + "/").addParam(s); err != nil {
+					return 0, err
+				}
+			case catchAllSegment:
+				if n, err = n.addStatic(static).addCatchAll(s); err != nil {
+					return 0, err
+				}
+			}
+			static = ""
+			params++
+		}
+		if len(variant) == 0 {
+			static = "/"
+		}
+
+		n = n.addStatic(static)
+		if n.route == nil {
+			n.route = route
+		} else if versions := n.route.addVersion(route); versions != nil {
+			n.route = versions
+		} else {
+			return 0, errors.New("a route is already registered for " + route.Method + " " + n.route.Path)
+		}
+
+		if params > maxParams {
+			maxParams = params
+		}
+	}
+
+	return maxParams, nil
+}
+
+// conflict returns an error if the route can not be registered under the path
+// of the given segments below the node. It does not change the tree.
+func (n *node) conflict(route *Route, segments []segment) error {
+	static := ""
+	for _, s := range segments {
+		var err error
+		switch s.kind {
+		case staticSegment:
+			static += "/" + s.value
+			continue
+		case paramSegment:
+			if n = n.staticChild(static + "/"); n != nil {
+				n, err = n.param(s)
+			}
+		case catchAllSegment:
+			if n = n.staticChild(static); n != nil {
+				n, err = n.catchAllChild(s)
+			}
+		}
+		if n == nil || err != nil {
+			// New nodes can not conflict with registered paths.
+			return err
+		}
+		static = ""
+	}
+	if len(segments) == 0 {
+		static = "/"
+	}
+
+	n = n.staticChild(static)
+	if n != nil && n.route != nil && !n.route.versionable(route) {
+		return errors.New("a route is already registered for " + route.Method + " " + n.route.Path)
+	}
+	return nil
+}
+
+// staticChild returns the node matching exactly the given static path below
+// the node or nil if there is none.
+func (n *node) staticChild(p string) *node {
+	for len(p) > 0 {
+		i := strings.IndexByte(n.indices, p[0])
+		if i < 0 || !strings.HasPrefix(p, n.static[i].path) {
+			return nil
+		}
+		n, p = n.static[i], p[len(n.static[i].path):]
+	}
+	return n
+}
+
+// addStatic returns the node matching the given static path below the node.
+// Nodes are split if they share a common prefix with the path.
+func (n *node) addStatic(p string) *node {
+	for len(p) > 0 {
+		i := strings.IndexByte(n.indices, p[0])
+		if i < 0 {
+			child := &node{path: p}
+			n.indices += p[:1]
+			n.static = append(n.static, child)
+			return child
+		}
+
+		child := n.static[i]
+		l := 0
+		for l < len(child.path) && l < len(p) && child.path[l] == p[l] {
+			l++
+		}
+
+		if l < len(child.path) {
+			split := *child
+			split.path = child.path[l:]
+			*child = node{
+				path:    child.path[:l],
+				indices: split.path[:1],
+				static:  []*node{&split},
+			}
+		}
+
+		n, p = child, p[l:]
+	}
+	return n
+}
+
+// addParam returns the parameter node for the given segment below the node.
+// Parameters at the same position must have the same name unless their
+// constraints differ.
+func (n *node) addParam(s segment) (*node, error) {
+	if child, err := n.param(s); child != nil || err != nil {
+		return child, err
+	}
+
+	child := &node{name: s.value, expr: s.expr, constraint: s.constraint}
+
+	// Constrained parameters are matched before the unconstrained one.
+	i := len(n.params)
+	if s.constraint != nil && i > 0 && n.params[i-1].constraint == nil {
+		i--
+	}
+	n.params = append(n.params, nil)
+	copy(n.params[i+1:], n.params[i:])
+	n.params[i] = child
+
+	return child, nil
+}
+
+// param returns the registered parameter node for the given segment below the
+// node or nil if there is none.
+func (n *node) param(s segment) (*node, error) {
+	for _, child := range n.params {
+		if child.expr != s.expr {
+			continue
+		}
+		if child.name != s.value {
+			return nil, errors.New("wildcard ':" + s.value + "' conflicts with existing wildcard ':" + child.name + "'")
+		}
+		return child, nil
+	}
+	return nil, nil
+}
+
+// addCatchAll returns the catch-all node for the given segment below the node.
+func (n *node) addCatchAll(s segment) (*node, error) {
+	if child, err := n.catchAllChild(s); child != nil || err != nil {
+		return child, err
+	}
+	n.catchAll = &node{name: s.value}
+	return n.catchAll, nil
+}
+
+// catchAllChild returns the registered catch-all node for the given segment
+// below the node or nil if there is none.
+func (n *node) catchAllChild(s segment) (*node, error) {
+	if n.catchAll != nil && n.catchAll.name != s.value {
+		return nil, errors.New("catch-all wildcard '*" + s.value + "' conflicts with existing wildcard '*" + n.catchAll.name + "'")
+	}
+	return n.catchAll, nil
+}
+
+// match returns the route registered for the given method and path and
+// appends its parameters to ps. Static paths are compared case-insensitively
+// if fold is set. Matching does not allocate if ps has enough capacity for
+// the parameters.
+func (t *tree) match(method string, p string, ps *Params, fold bool) *Route {
+	root := t.root(method)
+	if root == nil {
+		return nil
+	}
+	return root.match(p, ps, fold)
+}
+
+// match returns the route matching the given path below the node. Static
+// paths are preferred over parameters, which are preferred over catch-all
+// parameters. If a branch does not lead to a route, the next one is tried.
+func (n *node) match(p string, ps *Params, fold bool) *Route {
+	if p == "" {
+		return n.route
+	}
+
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] != p[0] && !(fold && lowerASCII(n.indices[i]) == lowerASCII(p[0])) {
+			continue
+		}
+
+		child := n.static[i]
+		if hasPrefix(p, child.path, fold) {
+			if route := child.match(p[len(child.path):], ps, fold); route != nil {
+				return route
+			}
+		}
+	}
+
+	if len(n.params) > 0 {
+		end := strings.IndexByte(p, '/')
+		if end < 0 {
+			end = len(p)
+		}
+
+		if end > 0 {
+			value, l := p[:end], len(*ps)
+			for _, child := range n.params {
+				if child.constraint != nil && !child.constraint.MatchString(value) {
+					continue
+				}
+
+				*ps = append(*ps, Param{Key: child.name, Value: value})
+				if route := child.match(p[end:], ps, fold); route != nil {
+					return route
+				}
+				*ps = (*ps)[:l]
+			}
+		}
+	}
+
+	if n.catchAll != nil && n.catchAll.route != nil && p[0] == '/' {
+		*ps = append(*ps, Param{Key: n.catchAll.name, Value: p})
+		return n.catchAll.route
+	}
+
+	return nil
+}
+
+// hasPrefix reports whether the path begins with the prefix, ignoring the case
+// if fold is set.
+func hasPrefix(p string, prefix string, fold bool) bool {
+	if len(p) < len(prefix) {
+		return false
+	}
+	return p[:len(prefix)] == prefix || (fold && strings.EqualFold(p[:len(prefix)], prefix))
+}
+
+// lowerASCII returns the lower case of an ASCII letter.
+func lowerASCII(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
@@ -0,0 +1,236 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStaticOverParam(t *testing.T) {
+	k := New()
+	k.GET("/users/new", "users::new", func(c *Context, r *Response) { r.Text("new") })
+	k.GET("/users/:id", "users::show", func(c *Context, r *Response) { r.Text("show:", c.Param("id")) })
+	k.GET("/users/:id/edit", "users::edit", func(c *Context, r *Response) { r.Text("edit:", c.Param("id")) })
+	k.GET("/users/new/edit", "users::edit_new", func(c *Context, r *Response) { r.Text("edit new") })
+	k.GET("/users/news", "users::news", func(c *Context, r *Response) { r.Text("news") })
+
+	if err := k.Validate(); err != nil {
+		t.Fatalf("Validate should return nil but got %v", err)
+	}
+
+	tests := map[string]string{
+		"/users/new":      "new",
+		"/users/news":     "news",
+		"/users/newer":    "show:newer",
+		"/users/42":       "show:42",
+		"/users/42/edit":  "edit:42",
+		"/users/new/edit": "edit new",
+	}
+
+	for p, body := range tests {
+		r, _ := http.NewRequest("GET", p, nil)
+		check(k, r, t, body)
+	}
+}
+
+func TestBacktracking(t *testing.T) {
+	k := New()
+	k.GET("/users/new/edit", "", func(c *Context, r *Response) { r.Text("edit new") })
+	k.GET("/users/:id/posts", "", func(c *Context, r *Response) { r.Text("posts:", c.Param("id")) })
+
+	r, _ := http.NewRequest("GET", "/users/new/posts", nil)
+	check(k, r, t, "posts:new")
+}
+
+func TestRegexParams(t *testing.T) {
+	k := New()
+	k.GET("/posts/:slug", "", func(c *Context, r *Response) { r.Text("slug:", c.Param("slug")) })
+	k.GET("/posts/:id<int>", "", func(c *Context, r *Response) { r.Text("id:", c.Param("id")) })
+	k.GET("/posts/:date<[0-9]{4}-[0-9]{2}>", "", func(c *Context, r *Response) { r.Text("date:", c.Param("date")) })
+
+	tests := map[string]string{
+		"/posts/42":      "id:42",
+		"/posts/2016-05": "date:2016-05",
+		"/posts/hello":   "slug:hello",
+	}
+
+	for p, body := range tests {
+		r, _ := http.NewRequest("GET", p, nil)
+		check(k, r, t, body)
+	}
+}
+
+func TestOptionalParams(t *testing.T) {
+	k := New()
+	k.GET("/posts/:page<int>?", "posts", func(c *Context, r *Response) { r.Text("page:", c.Param("page")) })
+	k.GET("/archive/:year?/:month?", "archive", func(c *Context, r *Response) {
+		r.Text(c.Param("year"), ":", c.Param("month"))
+	})
+
+	tests := map[string]string{
+		"/posts":           "page:",
+		"/posts/2":         "page:2",
+		"/archive":         ":",
+		"/archive/2016":    "2016:",
+		"/archive/2016/05": "2016:05",
+	}
+
+	for p, body := range tests {
+		r, _ := http.NewRequest("GET", p, nil)
+		check(k, r, t, body)
+	}
+
+	r, _ := http.NewRequest("GET", "/posts/two", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status should be 404 but got %d", w.Code)
+	}
+
+	if k.URL("posts") != "/posts" {
+		t.Errorf("URL should be /posts but got %s", k.URL("posts"))
+	}
+	if k.URL("posts", "page", "3") != "/posts/3" {
+		t.Errorf("URL should be /posts/3 but got %s", k.URL("posts", "page", "3"))
+	}
+}
+
+func TestCatchAll(t *testing.T) {
+	k := New()
+	k.GET("/files/*filepath", "", func(c *Context, r *Response) { r.Text("file:", c.Param("filepath")) })
+	k.GET("/files/index", "", func(c *Context, r *Response) { r.Text("index") })
+
+	tests := map[string]string{
+		"/files/":            "file:/",
+		"/files/index":       "index",
+		"/files/css/app.css": "file:/css/app.css",
+	}
+
+	for p, body := range tests {
+		r, _ := http.NewRequest("GET", p, nil)
+		check(k, r, t, body)
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	k := New()
+	k.GET("/users", "", func(c *Context, r *Response) {})
+	k.POST("/posts", "", func(c *Context, r *Response) {})
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{"GET", "/users/", http.StatusMovedPermanently, "/users"},
+		{"GET", "/../users", http.StatusMovedPermanently, "/users"},
+		{"POST", "/posts/", http.StatusPermanentRedirect, "/posts"},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest(test.method, test.path, nil)
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Code != test.code || w.Header().Get("Location") != test.location {
+			t.Errorf("%s %s should redirect with %d to %s but got %d %s", test.method, test.path, test.code, test.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestTreeConflicts(t *testing.T) {
+	k := New()
+	c := func(c *Context, r *Response) {}
+
+	k.GET("/users/:id", "", c)
+	k.POST("/users/:name", "", c)
+	k.GET("/users/:id<int>", "", c)
+	k.GET("/files/*filepath", "", c)
+
+	paths := []string{
+		"/users/:id",
+		"/users/:name/edit",
+		"/files/*path",
+		"/users/:id?",
+		"/files/*filepath/edit",
+		"/posts/:page?/edit",
+		"/users_:id",
+	}
+
+	for _, p := range paths {
+		if registerError(func() { k.GET(p, "", c) }) == nil {
+			t.Errorf("Registering %s should panic with a RouteError.", p)
+		}
+	}
+}
+
+func TestOptionalParamConflict(t *testing.T) {
+	k := New()
+	k.GET("/users", "users", func(c *Context, r *Response) { r.Text("users") })
+
+	err := registerError(func() {
+		k.GET("/users/:id?", "user", func(c *Context, r *Response) { r.Text("user") })
+	})
+	if err == nil {
+		t.Fatal("Registering a conflicting optional parameter should panic with a RouteError.")
+	}
+
+	// No variant of the rejected route is served.
+	r, _ := http.NewRequest("GET", "/users/5", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status should be 404 but got %d", w.Code)
+	}
+
+	r, _ = http.NewRequest("GET", "/users", nil)
+	check(k, r, t, "users")
+
+	if len(k.RouteList()) != 1 {
+		t.Errorf("Route list should contain 1 route but got %d", len(k.RouteList()))
+	}
+}
+
+func TestMatchAllocs(t *testing.T) {
+	k := New()
+	k.GET("/", "", func(c *Context, r *Response) {})
+	k.GET("/users/:id<int>/posts/:slug", "", func(c *Context, r *Response) {})
+	k.GET("/users/new", "", func(c *Context, r *Response) {})
+	k.GET("/files/*filepath", "", func(c *Context, r *Response) {})
+
+	ps := make(Params, 0, k.maxParams)
+	allocs := testing.AllocsPerRun(100, func() {
+		ps = ps[:0]
+		if k.tree.match("GET", "/users/42/posts/hello", &ps, false) == nil {
+			t.Fatal("Route should match.")
+		}
+		ps = ps[:0]
+		k.tree.match("GET", "/files/css/app.css", &ps, false)
+	})
+
+	if allocs != 0 {
+		t.Errorf("Match should not allocate but got %v allocations", allocs)
+	}
+
+	if ps.ByName("filepath") != "/css/app.css" {
+		t.Errorf("Param should be /css/app.css but got %s", ps.ByName("filepath"))
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	k := New()
+	k.GET("/users/:id/posts/:slug", "", func(c *Context, r *Response) {})
+	k.GET("/users/new", "", func(c *Context, r *Response) {})
+
+	ps := make(Params, 0, k.maxParams)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ps = ps[:0]
+		k.tree.match("GET", "/users/42/posts/hello", &ps, false)
+	}
+}
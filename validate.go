@@ -0,0 +1,104 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// A RouteError describes a route which could not be registered or whose
+// name is already taken.
+type RouteError struct {
+	// Route is the route which could not be registered.
+	Route *Route
+
+	// Existing is the registered route the route conflicts with. It is nil
+	// if the conflicting route is unknown.
+	Existing *Route
+
+	// Reason describes the conflict.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *RouteError) Error() string {
+	msg := fmt.Sprintf("kallisto: %s %s registered at %s: %s", e.Route.Method, e.Route.Path, e.Route.Source, e.Reason)
+	if e.Existing != nil {
+		msg += fmt.Sprintf(" (conflicts with %s %s registered at %s)", e.Existing.Method, e.Existing.Path, e.Existing.Source)
+	}
+	return msg
+}
+
+// RouteErrors is a list of route registration errors.
+type RouteErrors []error
+
+// Error implements the error interface.
+func (e RouteErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Validate returns the errors of all routes registered with a duplicate name
+// as RouteErrors. It returns nil if all route names are unique.
+//
+// Invalid and conflicting paths are not collected: such a route can not be
+// served, so Handle panics with its RouteError when it is registered, even
+// if Validate is never called.
+//
+// It is called by ListenAndServe before the server is started.
+func (k *Kallisto) Validate() error {
+	if len(k.errors) == 0 {
+		return nil
+	}
+	return k.errors
+}
+
+// conflictingRoute returns the registered route with the same method and host
+// and the longest common path prefix as the given route.
+func (k *Kallisto) conflictingRoute(route *Route) *Route {
+	var conflicting *Route
+	longest := -1
+	for _, r := range k.routeList {
+		if r.Method != route.Method || r.Host != route.Host {
+			continue
+		}
+		n := 0
+		for n < len(r.Path) && n < len(route.Path) && r.Path[n] == route.Path[n] {
+			n++
+		}
+		if n > longest {
+			conflicting, longest = r, n
+		}
+	}
+	return conflicting
+}
+
+// packageDir is the directory of the kallisto source files.
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// callerSource returns the file and line of the first caller outside of the
+// kallisto package.
+func callerSource() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != packageDir || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidateDuplicateName(t *testing.T) {
+	k := New()
+	c := func(c *Context, r *Response) { r.Text("first") }
+
+	k.GET("/first", "index", c)
+	k.GET("/second", "index", func(c *Context, r *Response) { r.Text("second") })
+
+	err := k.Validate()
+	if err == nil {
+		t.Fatal("Validate should return an error.")
+	}
+
+	errs := err.(RouteErrors)
+	if len(errs) != 1 {
+		t.Fatalf("Validate should return 1 error but got %d", len(errs))
+	}
+
+	routeErr := errs[0].(*RouteError)
+	if routeErr.Existing.Path != "/first" || routeErr.Route.Path != "/second" {
+		t.Errorf("Error should report /second conflicting with /first but got %v", routeErr)
+	}
+
+	if !strings.Contains(err.Error(), "validate_test.go:") {
+		t.Errorf("Error should contain the source location but got %s", err.Error())
+	}
+
+	if k.Route("index") != "/first" {
+		t.Errorf("Route path should be /first but got %s", k.Route("index"))
+	}
+
+	// The route is served anyway.
+	r, _ := http.NewRequest("GET", "/second", nil)
+	check(k, r, t, "second")
+
+	if len(k.RouteList()) != 2 {
+		t.Errorf("Route list should contain 2 routes but got %d", len(k.RouteList()))
+	}
+}
+
+func TestConflictingPath(t *testing.T) {
+	k := New()
+	c := func(c *Context, r *Response) {}
+
+	k.GET("/users/:id", "users::show", c)
+	k.POST("/users/:name", "users::update", c)
+
+	err := registerError(func() { k.GET("/users/:name/edit", "users::edit", c) })
+	if err == nil {
+		t.Fatal("Registering a conflicting path should panic with a RouteError.")
+	}
+
+	if err.Existing == nil || err.Existing.Path != "/users/:id" {
+		t.Errorf("Error should report the conflict with /users/:id but got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "validate_test.go:") {
+		t.Errorf("Error should contain the source location but got %s", err.Error())
+	}
+
+	if k.Routes()["users::edit"] != nil {
+		t.Error("Conflicting route should not be registered.")
+	}
+
+	if err := k.Validate(); err != nil {
+		t.Errorf("Validate should return nil but got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	k := New()
+	k.GET("/", "index", func(c *Context, r *Response) {})
+
+	if err := k.Validate(); err != nil {
+		t.Errorf("Validate should return nil but got %v", err)
+	}
+}
+
+// registerError returns the RouteError the registration panics with or nil.
+func registerError(register func()) (err *RouteError) {
+	defer func() {
+		err, _ = recover().(*RouteError)
+	}()
+	register()
+	return nil
+}
@@ -0,0 +1,301 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of versioning schemes.
+const (
+	pathVersioning = iota
+	headerVersioning
+	mediaTypeVersioning
+)
+
+// A VersionScheme decides how the requested API version is determined.
+type VersionScheme struct {
+	kind int
+
+	// name is the name of the header or media type parameter.
+	name string
+}
+
+// PathVersion versions routes by a path prefix, e.g. "/v2/users".
+func PathVersion() VersionScheme {
+	return VersionScheme{kind: pathVersioning}
+}
+
+// HeaderVersion versions routes by the given request header, e.g.
+// "API-Version: 2". The served version is sent in the same response header.
+func HeaderVersion(header string) VersionScheme {
+	return VersionScheme{kind: headerVersioning, name: http.CanonicalHeaderKey(header)}
+}
+
+// MediaTypeVersion versions routes by the given parameter of the media types
+// in the Accept header, e.g. "Accept: application/json; version=2".
+func MediaTypeVersion(param string) VersionScheme {
+	return VersionScheme{kind: mediaTypeVersioning, name: strings.ToLower(param)}
+}
+
+// An APIVersion is a version of the routes of an API.
+type APIVersion struct {
+	// Version is the version as registered, e.g. "2" or "2.1".
+	Version string
+
+	// Deprecated adds a "Deprecation: true" header to all responses of the version.
+	Deprecated bool
+
+	// Sunset is the time the version is removed. It is sent in the Sunset
+	// header if it is not zero.
+	Sunset time.Time
+
+	// Link is the URL of documentation about the deprecation of the version.
+	// It is sent in a Link header with the relation type "deprecation".
+	Link string
+
+	major, minor int
+	scheme       VersionScheme
+
+	// prefix is the path prefix of a PathVersion version.
+	prefix string
+}
+
+// Deprecate marks the version as deprecated and sets its sunset time, which
+// may be zero if it is not known yet.
+func (v *APIVersion) Deprecate(sunset time.Time) *APIVersion {
+	v.Deprecated = true
+	v.Sunset = sunset
+	return v
+}
+
+// writeHeaders sets the version and deprecation headers of the response.
+func (v *APIVersion) writeHeaders(h http.Header) {
+	switch v.scheme.kind {
+	case headerVersioning:
+		h.Set(v.scheme.name, v.Version)
+		h.Add("Vary", v.scheme.name)
+	case mediaTypeVersioning:
+		h.Add("Vary", "Accept")
+	}
+
+	if v.Deprecated {
+		h.Set("Deprecation", "true")
+	}
+	if !v.Sunset.IsZero() {
+		h.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if v.Link != "" {
+		h.Add("Link", "<"+v.Link+`>; rel="deprecation"`)
+	}
+}
+
+// newer reports whether the version is newer than the given one.
+func (v *APIVersion) newer(o *APIVersion) bool {
+	if v.major != o.major {
+		return v.major > o.major
+	}
+	return v.minor > o.minor
+}
+
+// compatible reports whether the version can serve a request for the given
+// major and minor version, i.e. it has the same major and at most the given
+// minor version. A negative minor version accepts any minor version.
+func (v *APIVersion) compatible(major int, minor int) bool {
+	return v.major == major && (minor < 0 || v.minor <= minor)
+}
+
+// parseVersion parses a version of the form "2" or "2.1" with an optional "v"
+// prefix. The minor version is -1 if it is omitted.
+func parseVersion(s string) (int, int, bool) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	majorPart, minorPart, hasMinor := strings.Cut(s, ".")
+
+	major, err := strconv.Atoi(majorPart)
+	if err != nil || major < 0 {
+		return 0, 0, false
+	}
+	if !hasMinor {
+		return major, -1, true
+	}
+
+	minor, err := strconv.Atoi(minorPart)
+	if err != nil || minor < 0 {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// Version registers the routes of fn as the given version of the API, e.g.
+// "1" or "2.1", which is selected by the given scheme. A leading "v" of the
+// version is ignored, so "v2" is the same as "2". The routes are named
+// with the prefix "v<version>::", e.g. "v2::users::index". For PathVersion
+// their paths are prefixed by "/v<version>" as well.
+//
+// Requests select the newest version with the same major version and at most
+// the requested minor version. Requests without a version are served by the
+// newest version which registered the route. For PathVersion routes missing
+// in the requested version fall back to older compatible versions.
+//
+// The served version is available via Context.APIVersion.
+func (r *Router) Version(scheme VersionScheme, version string, fn func(*Router)) *APIVersion {
+	major, minor, ok := parseVersion(version)
+	if !ok {
+		panic("invalid API version '" + version + "'")
+	}
+	if minor < 0 {
+		minor = 0
+	}
+	if version[0] == 'v' || version[0] == 'V' {
+		version = version[1:]
+	}
+
+	v := &APIVersion{Version: version, major: major, minor: minor, scheme: scheme}
+	r.kallisto.versions = append(r.kallisto.versions, v)
+
+	pathPrefix := ""
+	if scheme.kind == pathVersioning {
+		pathPrefix = "/v" + version
+		v.prefix = path.Join(r.pathPrefix, pathPrefix)
+	}
+
+	r.Group(pathPrefix, "v"+version+"::", func(g *Router) {
+		g.version = v
+		fn(g)
+	})
+
+	return v
+}
+
+// APIVersion returns the version of the API which serves the request or nil
+// if the route is not versioned.
+func (c *Context) APIVersion() *APIVersion {
+	if c.route == nil {
+		return nil
+	}
+	return c.route.version
+}
+
+// addVersion adds the route as another version of the route registered for the
+// same method and path. It returns the route which holds all versions and is
+// stored in the tree instead, or nil if both routes are not versions of the
+// same API which are selected by a request header.
+//
+// The versions are held by a route per path, so the paths of a route with
+// optional parameters only serve the versions registered for them.
+func (r *Route) addVersion(route *Route) *Route {
+	if !r.versionable(route) {
+		return nil
+	}
+
+	set := r
+	if r.versions == nil {
+		set = &Route{Method: r.Method, Path: r.Path, version: r.version, versions: []*Route{r}}
+	}
+	set.versions = append(set.versions, route)
+	sort.SliceStable(set.versions, func(i, j int) bool {
+		return set.versions[i].version.newer(set.versions[j].version)
+	})
+
+	return set
+}
+
+// versionable reports whether the route can be added as another version of
+// the route by addVersion. Each version can only be registered once.
+func (r *Route) versionable(route *Route) bool {
+	if r.version == nil || route.version == nil || r.version.scheme != route.version.scheme ||
+		r.version.scheme.kind == pathVersioning {
+		return false
+	}
+
+	versions := r.versions
+	if versions == nil {
+		versions = []*Route{r}
+	}
+	for _, v := range versions {
+		if v.version.major == route.version.major && v.version.minor == route.version.minor {
+			return false
+		}
+	}
+	return true
+}
+
+// selectVersion returns the version of the route which serves the request or
+// nil if no version is compatible with the requested version.
+func (r *Route) selectVersion(req *http.Request) *Route {
+	requested := requestedVersion(req, r.version.scheme)
+
+	versions := r.versions
+	if versions == nil {
+		if requested == "" {
+			return r
+		}
+		versions = []*Route{r}
+	}
+
+	if requested == "" {
+		return versions[0]
+	}
+
+	major, minor, ok := parseVersion(requested)
+	if !ok {
+		return nil
+	}
+	for _, route := range versions {
+		if route.version.compatible(major, minor) {
+			return route
+		}
+	}
+	return nil
+}
+
+// requestedVersion returns the version requested by the header or the media
+// type parameter of the scheme.
+func requestedVersion(req *http.Request, scheme VersionScheme) string {
+	if scheme.kind == headerVersioning {
+		return strings.TrimSpace(req.Header.Get(scheme.name))
+	}
+
+	for _, accept := range req.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			if _, params, err := mime.ParseMediaType(mediaType); err == nil && params[scheme.name] != "" {
+				return params[scheme.name]
+			}
+		}
+	}
+	return ""
+}
+
+// serveVersionFallback serves a request to the path prefix of a PathVersion
+// version by the newest older compatible version which has a matching route.
+// It reports whether the request was served.
+func (k *Kallisto) serveVersionFallback(w http.ResponseWriter, req *http.Request, t *tree, p string) bool {
+	for _, v := range k.versions {
+		if v.prefix == "" || !hasPathPrefix(p, v.prefix) {
+			continue
+		}
+
+		var older []*APIVersion
+		for _, o := range k.versions {
+			if o.prefix != "" && path.Dir(o.prefix) == path.Dir(v.prefix) && v.newer(o) && o.compatible(v.major, v.minor) {
+				older = append(older, o)
+			}
+		}
+		sort.Slice(older, func(i, j int) bool { return older[i].newer(older[j]) })
+
+		for _, o := range older {
+			if k.serveRoute(w, req, t, o.prefix+p[len(v.prefix):]) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+// Copyright 2016 Swen Gorschewski. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package kallisto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func versioned(c *Context, r *Response) {
+	r.Text(c.APIVersion().Version)
+}
+
+func TestPathVersion(t *testing.T) {
+	k := New()
+	k.Group("/api", "api::", func(api *Router) {
+		api.Version(PathVersion(), "1", func(r *Router) {
+			r.GET("/users", "users", versioned)
+			r.GET("/posts", "posts", versioned)
+		})
+		api.Version(PathVersion(), "1.1", func(r *Router) {
+			r.GET("/users", "users", versioned)
+		})
+		api.Version(PathVersion(), "v2", func(r *Router) {
+			r.GET("/users", "users", versioned)
+		})
+	})
+
+	tests := map[string]string{
+		"/api/v1/users":   "1",
+		"/api/v1.1/users": "1.1",
+		"/api/v2/users":   "2",
+		"/api/v1.1/posts": "1",
+	}
+
+	for p, body := range tests {
+		r, _ := http.NewRequest("GET", p, nil)
+		check(k, r, t, body)
+	}
+
+	r, _ := http.NewRequest("GET", "/api/v2/posts", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status should be 404 but got %d", w.Code)
+	}
+
+	if k.URL("api::v2::users") != "/api/v2/users" {
+		t.Errorf("URL should be /api/v2/users but got %s", k.URL("api::v2::users"))
+	}
+}
+
+func TestHeaderVersion(t *testing.T) {
+	k := New()
+	scheme := HeaderVersion("API-Version")
+	k.Version(scheme, "1", func(r *Router) {
+		r.GET("/users", "users", versioned)
+		r.GET("/posts", "posts", versioned)
+	})
+	k.Version(scheme, "2.1", func(r *Router) {
+		r.GET("/users", "users", versioned)
+	})
+	k.Version(scheme, "2", func(r *Router) {
+		r.GET("/users", "users", versioned)
+	})
+
+	if err := k.Validate(); err != nil {
+		t.Fatalf("Validate should return nil but got %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		version string
+		body    string
+	}{
+		{"/users", "", "2.1"},
+		{"/users", "1", "1"},
+		{"/users", "2", "2.1"},
+		{"/users", "2.0", "2"},
+		{"/users", "2.5", "2.1"},
+		{"/posts", "", "1"},
+		{"/posts", "1.3", "1"},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", test.path, nil)
+		if test.version != "" {
+			r.Header.Set("API-Version", test.version)
+		}
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+
+		if w.Body.String() != test.body {
+			t.Errorf("Body for %s with version %q should be %s but got %s", test.path, test.version, test.body, w.Body.String())
+		}
+		if w.Header().Get("API-Version") != test.body {
+			t.Errorf("API-Version header should be %s but got %s", test.body, w.Header().Get("API-Version"))
+		}
+	}
+
+	for _, version := range []string{"3", "invalid"} {
+		r, _ := http.NewRequest("GET", "/posts", nil)
+		r.Header.Set("API-Version", version)
+		w := httptest.NewRecorder()
+		k.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Status for version %s should be 404 but got %d", version, w.Code)
+		}
+	}
+}
+
+func TestHeaderVersionConflict(t *testing.T) {
+	k := New()
+	scheme := HeaderVersion("API-Version")
+	k.Version(scheme, "1", func(r *Router) {
+		r.GET("/users/:id?", "users", versioned)
+	})
+	k.Version(scheme, "2", func(r *Router) {
+		r.GET("/users", "users", versioned)
+	})
+
+	// The version is already registered for /users.
+	err := registerError(func() {
+		k.Version(scheme, "2.0", func(r *Router) {
+			r.GET("/users/:id?", "user", versioned)
+		})
+	})
+	if err == nil {
+		t.Fatal("Registering a version twice should panic with a RouteError.")
+	}
+
+	// The rejected version is not served for /users/:id either.
+	r, _ := http.NewRequest("GET", "/users/5", nil)
+	check(k, r, t, "1")
+}
+
+func TestMediaTypeVersion(t *testing.T) {
+	k := New()
+	scheme := MediaTypeVersion("version")
+	k.Version(scheme, "1", func(r *Router) { r.GET("/users", "users", versioned) })
+	k.Version(scheme, "2", func(r *Router) { r.GET("/users", "users", versioned) })
+
+	r, _ := http.NewRequest("GET", "/users", nil)
+	r.Header.Set("Accept", "text/html, application/vnd.example+json; version=1")
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Body.String() != "1" {
+		t.Errorf("Body should be 1 but got %s", w.Body.String())
+	}
+	if w.Header().Get("Vary") != "Accept" {
+		t.Errorf("Vary header should be Accept but got %s", w.Header().Get("Vary"))
+	}
+
+	r, _ = http.NewRequest("GET", "/users", nil)
+	r.Header.Set("Accept", "application/json")
+	check(k, r, t, "2")
+}
+
+func TestDeprecatedVersion(t *testing.T) {
+	sunset := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	k := New()
+	v1 := k.Version(PathVersion(), "1", func(r *Router) { r.GET("/users", "users", versioned) })
+	v1.Deprecate(sunset).Link = "https://example.com/migration"
+	k.Version(PathVersion(), "2", func(r *Router) { r.GET("/users", "users", versioned) })
+
+	r, _ := http.NewRequest("GET", "/v1/users", nil)
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Header().Get("Deprecation") != "true" {
+		t.Errorf("Deprecation header should be true but got %s", w.Header().Get("Deprecation"))
+	}
+	if w.Header().Get("Sunset") != "Sun, 01 Jan 2017 00:00:00 GMT" {
+		t.Errorf("Sunset header should be Sun, 01 Jan 2017 00:00:00 GMT but got %s", w.Header().Get("Sunset"))
+	}
+	if w.Header().Get("Link") != `<https://example.com/migration>; rel="deprecation"` {
+		t.Errorf("Unexpected Link header %s", w.Header().Get("Link"))
+	}
+
+	r, _ = http.NewRequest("GET", "/v2/users", nil)
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, r)
+
+	if w.Header().Get("Deprecation") != "" || w.Header().Get("Sunset") != "" {
+		t.Error("Current version should not be deprecated.")
+	}
+}